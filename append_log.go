@@ -0,0 +1,164 @@
+package kickbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// appendLog is a file of JSON records, one per line, backing the in-memory state
+// of the file stores: the records are replayed on open, every change is appended
+// and the file is rewritten with the live records once most of them are stale.
+// It is not safe for concurrent use, the stores hold their lock.
+type appendLog struct {
+	path      string
+	name      string // e.g. "cache file", used in the errors
+	errClosed error  // returned when writing after close
+	sync      bool   // sync the file to disk after every append
+
+	file    *os.File
+	records int // records in the file, live or stale
+}
+
+// open opens the file, creating it if it does not exist, and replays its records.
+// A truncated last line, left by an interrupted write, is removed.
+func (l *appendLog) open(replay func(data []byte) error) error {
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening %s: %v", l.name, err)
+	}
+
+	if err := l.load(file, replay); err != nil {
+		file.Close()
+		return fmt.Errorf("loading %s %s: %v", l.name, l.path, err)
+	}
+
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return fmt.Errorf("opening %s: %v", l.name, err)
+	}
+	l.file = file
+	return nil
+}
+
+// load calls replay with every line of the file
+func (l *appendLog) load(file *os.File, replay func(data []byte) error) error {
+	reader := bufio.NewReader(file)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				return file.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(data))
+
+		if err := replay(data); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		l.records++
+	}
+}
+
+// append writes the record to the file
+func (l *appendLog) append(record interface{}) error {
+	if l.file == nil {
+		return l.errClosed
+	}
+	if err := writeRecord(l.file, record); err != nil {
+		return fmt.Errorf("writing %s: %v", l.name, err)
+	}
+	if l.sync {
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("writing %s: %v", l.name, err)
+		}
+	}
+	l.records++
+	return nil
+}
+
+// shouldCompact reports whether the stale records are more than minStale and than the live ones
+func (l *appendLog) shouldCompact(live, minStale int) bool {
+	stale := l.records - live
+	return stale >= minStale && stale >= live
+}
+
+// compact writes the records given by each to a temporary file replacing the current one
+func (l *appendLog) compact(each func(write func(record interface{}) error) error) error {
+	if l.file == nil {
+		return l.errClosed
+	}
+
+	tmp, err := os.OpenFile(l.path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("compacting %s: %v", l.name, err)
+	}
+
+	records := 0
+	bw := bufio.NewWriter(tmp)
+	err = each(func(record interface{}) error {
+		records++
+		return writeRecord(bw, record)
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(l.path + ".tmp")
+		return fmt.Errorf("compacting %s: %v", l.name, err)
+	}
+
+	// the file must be closed to be replaced on windows
+	l.file.Close()
+	if err := os.Rename(l.path+".tmp", l.path); err != nil {
+		os.Remove(l.path + ".tmp")
+		l.file, _ = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o600)
+		return fmt.Errorf("compacting %s: %v", l.name, err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		l.file = nil
+		return fmt.Errorf("compacting %s: %v", l.name, err)
+	}
+	l.file = file
+	l.records = records
+	return nil
+}
+
+// close flushes the file to disk and releases it, closing it twice is safe
+func (l *appendLog) close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Sync()
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	return err
+}
+
+// writeRecord writes the record as a single line, in one write call
+func writeRecord(w io.Writer, record interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(record); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
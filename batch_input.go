@@ -0,0 +1,256 @@
+package kickbox
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// BatchInputStats are the figures of a BatchInput once read
+type BatchInputStats struct {
+	Rows       int // entries read from the source
+	Written    int // rows written to the CSV file
+	Empty      int // entries skipped because the email was empty
+	Duplicates int // entries skipped by Deduplicate
+	Invalid    int // entries skipped by FilterInvalidSyntax
+}
+
+// batchRow is an entry of a batch input, the email and the passthrough columns
+type batchRow struct {
+	email string
+	extra []string
+}
+
+// batchSource sends the rows to emit until the source is exhausted, emit errors must be returned
+type batchSource func(ctx context.Context, emit func(batchRow) error) error
+
+// BatchInput builds the CSV file of a batch job from a slice, a channel or a column of
+// another CSV, streaming it without holding the whole file in memory.
+//
+//	input := kickbox.BatchInputFromCSV(file, "Email", "CustomerID").Deduplicate().FilterInvalidSyntax()
+//	resp, err := client.VerifyBatchInput(ctx, input)
+//
+// A BatchInput can be read only once.
+type BatchInput struct {
+	source      batchSource
+	header      []string // written as the first row when not nil
+	deduplicate bool
+	validate    bool
+
+	mu    sync.Mutex
+	stats BatchInputStats
+}
+
+// BatchInputFromSlice builds a CSV file with an email per row
+func BatchInputFromSlice(emails []string) *BatchInput {
+	return &BatchInput{
+		source: func(ctx context.Context, emit func(batchRow) error) error {
+			for _, email := range emails {
+				if err := emit(batchRow{email: email}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// BatchInputFromChan builds a CSV file with an email per row, reading the channel until it is closed
+func BatchInputFromChan(emails <-chan string) *BatchInput {
+	return &BatchInput{
+		source: func(ctx context.Context, emit func(batchRow) error) error {
+			for {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case email, ok := <-emails:
+					if !ok {
+						return nil
+					}
+					if err := emit(batchRow{email: email}); err != nil {
+						return err
+					}
+				}
+			}
+		},
+	}
+}
+
+// BatchInputFromCSV extracts the email column of a CSV with headers. The passthrough columns
+// are copied after the email, with a header row, so they are returned with the results.
+// Headers are matched case insensitive.
+func BatchInputFromCSV(r io.Reader, column string, passthrough ...string) *BatchInput {
+	in := &BatchInput{}
+	if len(passthrough) > 0 {
+		in.header = append([]string{column}, passthrough...)
+	}
+	in.source = func(ctx context.Context, emit func(batchRow) error) error {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+
+		header, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading csv header: %v", err)
+		}
+		emailIndex, err := columnIndex(header, column)
+		if err != nil {
+			return err
+		}
+		extraIndexes := make([]int, len(passthrough))
+		for i, name := range passthrough {
+			if extraIndexes[i], err = columnIndex(header, name); err != nil {
+				return err
+			}
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading csv: %v", err)
+			}
+
+			row := batchRow{email: field(record, emailIndex), extra: make([]string, len(extraIndexes))}
+			for i, index := range extraIndexes {
+				row.extra[i] = field(record, index)
+			}
+			if err := emit(row); err != nil {
+				return err
+			}
+		}
+	}
+	return in
+}
+
+// columnIndex returns the position of the column in the header
+func columnIndex(header []string, column string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("csv column %q not found", column)
+}
+
+// field returns the value of the record at index, empty when the row is shorter
+func field(record []string, index int) string {
+	if index < len(record) {
+		return record[index]
+	}
+	return ""
+}
+
+// Deduplicate skips the emails already written, compared normalized with the local part lowercased
+func (in *BatchInput) Deduplicate() *BatchInput {
+	in.deduplicate = true
+	return in
+}
+
+// FilterInvalidSyntax skips the emails failing ValidateSyntax, they would be charged as undeliverable
+func (in *BatchInput) FilterInvalidSyntax() *BatchInput {
+	in.validate = true
+	return in
+}
+
+// Stats returns the figures of the rows read so far
+func (in *BatchInput) Stats() BatchInputStats {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.stats
+}
+
+// Reader streams the CSV file. Reading fails with the error of the source, if any.
+// Closing the reader stops the source.
+func (in *BatchInput) Reader(ctx context.Context) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(in.write(ctx, pw))
+	}()
+	return pr
+}
+
+// write writes the CSV file to w
+func (in *BatchInput) write(ctx context.Context, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if in.header != nil {
+		if err := cw.Write(in.header); err != nil {
+			return err
+		}
+	}
+
+	if err := in.each(ctx, cw.Write); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// each calls fn with the CSV fields of every row accepted by the filters, the header excluded
+func (in *BatchInput) each(ctx context.Context, fn func(fields []string) error) error {
+	seen := map[string]struct{}{}
+	return in.source(ctx, func(row batchRow) error {
+		email := strings.TrimSpace(row.email)
+		if !in.accept(email, seen) {
+			return nil
+		}
+		return fn(append([]string{email}, row.extra...))
+	})
+}
+
+// accept reports whether the email is written, counting it in the stats
+func (in *BatchInput) accept(email string, seen map[string]struct{}) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.stats.Rows++
+	switch {
+	case email == "":
+		in.stats.Empty++
+		return false
+	case in.validate && ValidateSyntax(email) != nil:
+		in.stats.Invalid++
+		return false
+	}
+	if in.deduplicate {
+		key := EmailKey(email)
+		if _, found := seen[key]; found {
+			in.stats.Duplicates++
+			return false
+		}
+		seen[key] = struct{}{}
+	}
+	in.stats.Written++
+	return true
+}
+
+// VerifyBatchEmails submits a batch job verifying the emails, see VerifyBatchInput
+func (c *ClientHTTP) VerifyBatchEmails(ctx context.Context, emails []string, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
+	return c.VerifyBatchInput(ctx, BatchInputFromSlice(emails), opts...)
+}
+
+// VerifyBatchInput submits a batch job with the CSV file built by the input.
+// The file is streamed, so the upload is not retried.
+func (c *ClientHTTP) VerifyBatchInput(ctx context.Context, input *BatchInput, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
+	return c.VerifyBatch(ctx, input.Reader(ctx), opts...)
+}
+
+// VerifyBatchEmails creates a sandbox batch job verifying the emails
+func (c *ClientSandbox) VerifyBatchEmails(ctx context.Context, emails []string, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
+	return c.VerifyBatchInput(ctx, BatchInputFromSlice(emails), opts...)
+}
+
+// VerifyBatchInput creates a sandbox batch job with the CSV file built by the input
+func (c *ClientSandbox) VerifyBatchInput(ctx context.Context, input *BatchInput, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
+	return c.VerifyBatch(ctx, input.Reader(ctx), opts...)
+}
@@ -0,0 +1,115 @@
+package kickbox_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchInputFromSlice(t *testing.T) {
+	input := kickbox.BatchInputFromSlice([]string{
+		" user@example.com ",
+		"",
+		"User@Example.com",
+		"not an email",
+		"other@example.com",
+	}).Deduplicate().FilterInvalidSyntax()
+
+	content, err := io.ReadAll(input.Reader(context.TODO()))
+	assert.Nil(t, err)
+	assert.Equal(t, "user@example.com\nother@example.com\n", string(content))
+	assert.Equal(t, kickbox.BatchInputStats{Rows: 5, Written: 2, Empty: 1, Duplicates: 1, Invalid: 1}, input.Stats())
+}
+
+func TestBatchInputFromChan(t *testing.T) {
+	emails := make(chan string)
+	go func() {
+		defer close(emails)
+		emails <- "user@example.com"
+		emails <- "user@example.com"
+	}()
+
+	content, err := io.ReadAll(kickbox.BatchInputFromChan(emails).Reader(context.TODO()))
+	assert.Nil(t, err)
+	assert.Equal(t, "user@example.com\nuser@example.com\n", string(content))
+
+	// a done context stops reading the channel
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err = io.ReadAll(kickbox.BatchInputFromChan(make(chan string)).Reader(ctx))
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBatchInputFromCSV(t *testing.T) {
+	const source = `Name,Customer ID,EMAIL,Plan
+Bill,1,bill@example.com,pro
+"Lumbergh, Bill",2,bill@example.com,free
+Milton,3
+`
+	input := kickbox.BatchInputFromCSV(strings.NewReader(source), "email", "name", "customer id")
+	content, err := io.ReadAll(input.Reader(context.TODO()))
+	assert.Nil(t, err)
+	assert.Equal(t, "email,name,customer id\nbill@example.com,Bill,1\nbill@example.com,\"Lumbergh, Bill\",2\n", string(content))
+	assert.Equal(t, kickbox.BatchInputStats{Rows: 3, Written: 2, Empty: 1}, input.Stats())
+
+	// without passthrough columns there is no header
+	content, err = io.ReadAll(kickbox.BatchInputFromCSV(strings.NewReader(source), "Email").Deduplicate().Reader(context.TODO()))
+	assert.Nil(t, err)
+	assert.Equal(t, "bill@example.com\n", string(content))
+
+	_, err = io.ReadAll(kickbox.BatchInputFromCSV(strings.NewReader(source), "email", "phone").Reader(context.TODO()))
+	assert.EqualError(t, err, `csv column "phone" not found`)
+}
+
+func TestVerifyBatchEmails(t *testing.T) {
+	var uploaded string
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		uploaded = string(content)
+		_, _ = rw.Write([]byte(`{"id":123,"success":true}`))
+	}
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey", kickbox.OverrideBaseURL(svr.URL))
+	assert.Nil(t, err)
+
+	resp, err := client.VerifyBatchEmails(context.TODO(), []string{"a@example.com", "b@example.com"}, kickbox.Filename("test"))
+	assert.Nil(t, err)
+	assert.Equal(t, 123, resp.ID)
+	assert.Equal(t, "a@example.com\nb@example.com\n", uploaded)
+
+	// the source error fails the upload
+	input := kickbox.BatchInputFromCSV(strings.NewReader("name\nbill\n"), "email")
+	_, err = client.VerifyBatchInput(context.TODO(), input)
+	assert.Contains(t, err.Error(), `csv column "email" not found`)
+}
+
+func TestSandboxVerifyBatchEmails(t *testing.T) {
+	sandbox := kickbox.NewSandbox()
+
+	resp, err := sandbox.VerifyBatchEmails(context.TODO(), []string{"deliverable@example.com", "undeliverable@example.com"})
+	assert.Nil(t, err)
+
+	check, err := sandbox.VerifyBatchCheck(context.TODO(), "123456")
+	assert.Nil(t, err)
+	assert.Equal(t, resp.ID, check.ID)
+	assert.Equal(t, 2, check.Stats.Addresses)
+	assert.Equal(t, 1, check.Stats.Undeliverable)
+
+	input := kickbox.BatchInputFromCSV(strings.NewReader("id,email\n1,role@example.com\n2,ROLE@example.com\n"), "email", "id").Deduplicate()
+	_, err = sandbox.VerifyBatchInput(context.TODO(), input)
+	assert.Nil(t, err)
+
+	check, err = sandbox.VerifyBatchCheck(context.TODO(), "123457")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, check.Stats.Addresses)
+	assert.Equal(t, 1, check.Stats.Risky)
+}
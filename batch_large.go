@@ -0,0 +1,474 @@
+package kickbox
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// MaxBatchSize is the maximum number of addresses of a batch job
+// see: https://docs.kickbox.com/docs/batch-verification-api
+const MaxBatchSize = 1000000
+
+// LargeBatchRequestOptions holds the optional parameters for SubmitLargeBatch
+type LargeBatchRequestOptions struct {
+	chunkSize    int
+	concurrency  int
+	batchOptions []VerifyBatchOption
+}
+
+// LargeBatchOption option type
+type LargeBatchOption func(*LargeBatchRequestOptions)
+
+// ChunkSize sets the maximum number of addresses of every batch job. Default and maximum: MaxBatchSize
+func ChunkSize(n int) LargeBatchOption {
+	return func(o *LargeBatchRequestOptions) {
+		if n > 0 && n <= MaxBatchSize {
+			o.chunkSize = n
+		}
+	}
+}
+
+// SubmitConcurrency sets the number of chunks uploaded at the same time. The next chunk
+// is spooled while they upload, so up to n+1 temporary files of up to ChunkSize rows
+// exist at once. Default: 2
+func SubmitConcurrency(n int) LargeBatchOption {
+	return func(o *LargeBatchRequestOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithBatchOptions sets the options of every batch job, the Filename gets a " (part N)" suffix
+func WithBatchOptions(opts ...VerifyBatchOption) LargeBatchOption {
+	return func(o *LargeBatchRequestOptions) {
+		o.batchOptions = append(o.batchOptions, opts...)
+	}
+}
+
+// largeBatchClient is the client of a large batch, implemented by ClientHTTP and ClientSandbox
+type largeBatchClient interface {
+	VerifyBatch(ctx context.Context, file io.ReadCloser, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error)
+	VerifyBatchCheck(ctx context.Context, batchID string) (*VerifyBatchCheckResponse, error)
+	DownloadBatchResults(ctx context.Context, check *VerifyBatchCheckResponse) (*BatchResults, error)
+}
+
+// LargeBatchChunk is a batch job of a large batch
+type LargeBatchChunk struct {
+	Part      int // position of the chunk in the input, starting at 1
+	ID        int // batch job id
+	Addresses int // rows of the chunk
+}
+
+// LargeBatchCheckResponse is the status of a large batch, aggregating its chunks
+type LargeBatchCheckResponse struct {
+	// Status is "failed" when a chunk failed, "completed" when all of them are completed,
+	// "starting" when all of them are starting and "processing" otherwise
+	Status BatchStatus
+	// Progress of all the chunks, the completed ones included
+	Progress BatchProgress
+	// Stats of the completed chunks, the sendex is the average weighted by addresses
+	Stats BatchStats
+	// Chunks are the last status of every chunk, in the same order as LargeBatchJob.Chunks
+	Chunks []*VerifyBatchCheckResponse
+}
+
+// LargeBatchJob is a large batch split in several batch jobs
+type LargeBatchJob struct {
+	Chunks []LargeBatchChunk // sorted by part
+
+	client largeBatchClient
+
+	mu   sync.Mutex
+	last []*VerifyBatchCheckResponse // last status of every chunk
+}
+
+// LargeBatchJob rebuilds the job of a large batch from its chunks, e.g. persisted before
+// a restart, to check, wait for and download it
+func (c *ClientHTTP) LargeBatchJob(chunks ...LargeBatchChunk) *LargeBatchJob {
+	return newLargeBatchJob(c, chunks)
+}
+
+// LargeBatchJob rebuilds the job of a large sandbox batch, see ClientHTTP.LargeBatchJob
+func (c *ClientSandbox) LargeBatchJob(chunks ...LargeBatchChunk) *LargeBatchJob {
+	return newLargeBatchJob(c, chunks)
+}
+
+// newLargeBatchJob creates the job of the chunks, sorted by part
+func newLargeBatchJob(client largeBatchClient, chunks []LargeBatchChunk) *LargeBatchJob {
+	chunks = append([]LargeBatchChunk{}, chunks...)
+	sort.Slice(chunks, func(a, b int) bool { return chunks[a].Part < chunks[b].Part })
+	return &LargeBatchJob{
+		Chunks: chunks,
+		client: client,
+		last:   make([]*VerifyBatchCheckResponse, len(chunks)),
+	}
+}
+
+// IDs returns the batch job ids of the chunks
+func (j *LargeBatchJob) IDs() []int {
+	ids := make([]int, len(j.Chunks))
+	for i, chunk := range j.Chunks {
+		ids[i] = chunk.ID
+	}
+	return ids
+}
+
+// SubmitLargeBatch splits the input in chunks of up to MaxBatchSize addresses and submits
+// them as batch jobs, see ChunkSize and SubmitConcurrency. The chunks are spooled to
+// temporary files, so their upload is retried.
+// On error the job with the chunks already submitted is returned along with the error,
+// nil when none was submitted.
+func (c *ClientHTTP) SubmitLargeBatch(ctx context.Context, input *BatchInput, opts ...LargeBatchOption) (*LargeBatchJob, error) {
+	return submitLargeBatch(ctx, c, input, opts)
+}
+
+// SubmitLargeBatch splits the input in sandbox batch jobs, see ClientHTTP.SubmitLargeBatch
+func (c *ClientSandbox) SubmitLargeBatch(ctx context.Context, input *BatchInput, opts ...LargeBatchOption) (*LargeBatchJob, error) {
+	return submitLargeBatch(ctx, c, input, opts)
+}
+
+// spooledChunk is a chunk written to a temporary file, waiting to be submitted
+type spooledChunk struct {
+	part      int
+	path      string
+	addresses int
+}
+
+// submitLargeBatch spools the chunks while a pool of workers submits them
+func submitLargeBatch(ctx context.Context, client largeBatchClient, input *BatchInput, opts []LargeBatchOption) (*LargeBatchJob, error) {
+	options := LargeBatchRequestOptions{
+		chunkSize:   MaxBatchSize,
+		concurrency: 2,
+	}
+	for _, apply := range opts {
+		apply(&options)
+	}
+	batchOptions := VerifyBatchRequestOptions{}
+	for _, apply := range options.batchOptions {
+		apply(&batchOptions)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var submitted []LargeBatchChunk
+	var mu sync.Mutex
+	var submitErr error
+	chunks := make(chan spooledChunk)
+	wg := sync.WaitGroup{}
+	for i := 0; i < options.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				resp, err := submitChunk(ctx, client, chunk, options.batchOptions, batchOptions.filename)
+				os.Remove(chunk.path)
+
+				mu.Lock()
+				if err != nil {
+					if submitErr == nil {
+						submitErr = fmt.Errorf("submitting part %d: %w", chunk.part, err)
+					}
+					cancel()
+				} else {
+					submitted = append(submitted, LargeBatchChunk{Part: chunk.part, ID: resp.ID, Addresses: chunk.addresses})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	spoolErr := spoolChunks(ctx, input, options.chunkSize, func(chunk spooledChunk) error {
+		select {
+		case chunks <- chunk:
+			return nil
+		case <-ctx.Done():
+			os.Remove(chunk.path)
+			return ctx.Err()
+		}
+	})
+	close(chunks)
+	wg.Wait()
+
+	job := newLargeBatchJob(client, submitted)
+
+	err := submitErr
+	if err == nil && spoolErr != nil {
+		err = fmt.Errorf("reading input: %w", spoolErr)
+	}
+	switch {
+	case err != nil && len(job.Chunks) == 0:
+		return nil, err
+	case err != nil:
+		return job, err
+	case len(job.Chunks) == 0:
+		return nil, errors.New("no email addresses found")
+	}
+	return job, nil
+}
+
+// submitChunk uploads the chunk file as a batch job
+func submitChunk(ctx context.Context, client largeBatchClient, chunk spooledChunk, opts []VerifyBatchOption, filename string) (*ResponseVerifyBatch, error) {
+	file, err := os.Open(chunk.path)
+	if err != nil {
+		return nil, err
+	}
+	if filename != "" {
+		opts = append(opts[:len(opts):len(opts)], Filename(fmt.Sprintf("%s (part %d)", filename, chunk.part)))
+	}
+	resp, err := client.VerifyBatch(ctx, file, opts...)
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("batch not created: %s", resp.Message)
+	}
+	return resp, err
+}
+
+// spoolChunks writes the input to temporary files of up to size rows, passing every file
+// to emit once written. The input header, if any, is written on every file.
+func spoolChunks(ctx context.Context, input *BatchInput, size int, emit func(spooledChunk) error) error {
+	var (
+		file  *os.File
+		w     *csv.Writer
+		chunk spooledChunk
+	)
+
+	// done closes the current file and emits it
+	done := func() error {
+		w.Flush()
+		err := w.Error()
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		file = nil
+		if err != nil {
+			os.Remove(chunk.path)
+			return fmt.Errorf("writing chunk: %v", err)
+		}
+		return emit(chunk)
+	}
+
+	err := input.each(ctx, func(fields []string) error {
+		if file == nil {
+			var err error
+			if file, err = os.CreateTemp("", "kickbox-batch-*.csv"); err != nil {
+				return fmt.Errorf("creating chunk: %v", err)
+			}
+			w = csv.NewWriter(file)
+			chunk = spooledChunk{part: chunk.part + 1, path: file.Name()}
+			if input.header != nil {
+				if err := w.Write(input.header); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := w.Write(fields); err != nil {
+			return err
+		}
+		chunk.addresses++
+		if chunk.addresses == size {
+			return done()
+		}
+		return nil
+	})
+	if err != nil {
+		if file != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+		return err
+	}
+
+	if file != nil {
+		return done()
+	}
+	return nil
+}
+
+// Check checks the status of the chunks not completed nor failed yet and aggregates them
+func (j *LargeBatchJob) Check(ctx context.Context) (*LargeBatchCheckResponse, error) {
+	j.mu.Lock()
+	last := append([]*VerifyBatchCheckResponse{}, j.last...)
+	j.mu.Unlock()
+
+	for i, chunk := range j.Chunks {
+		if last[i] != nil && last[i].Status.IsTerminal() {
+			continue
+		}
+		resp, err := j.client.VerifyBatchCheck(ctx, strconv.Itoa(chunk.ID))
+		if err != nil {
+			return nil, fmt.Errorf("checking part %d, batch %d: %w", chunk.Part, chunk.ID, err)
+		}
+		last[i] = resp
+	}
+
+	j.mu.Lock()
+	j.last = last
+	j.mu.Unlock()
+
+	return j.aggregate(last), nil
+}
+
+// aggregate sums up the status of the chunks
+func (j *LargeBatchJob) aggregate(checks []*VerifyBatchCheckResponse) *LargeBatchCheckResponse {
+	resp := &LargeBatchCheckResponse{Chunks: checks}
+
+	count := map[BatchStatus]int{}
+	var sendex float64
+	for i, check := range checks {
+		count[check.Status]++
+
+		p := &resp.Progress
+		switch check.Status {
+		case BatchCompleted:
+			s := check.Stats
+			p.Deliverable += s.Deliverable
+			p.Undeliverable += s.Undeliverable
+			p.Risky += s.Risky
+			p.Unknown += s.Unknown
+			p.Total += s.Addresses
+
+			resp.Stats.Deliverable += s.Deliverable
+			resp.Stats.Undeliverable += s.Undeliverable
+			resp.Stats.Risky += s.Risky
+			resp.Stats.Unknown += s.Unknown
+			resp.Stats.Addresses += s.Addresses
+			sendex += s.Sendex * float64(s.Addresses)
+		case BatchProcessing:
+			p.Deliverable += check.Progress.Deliverable
+			p.Undeliverable += check.Progress.Undeliverable
+			p.Risky += check.Progress.Risky
+			p.Unknown += check.Progress.Unknown
+			p.Total += check.Progress.Total
+			p.Unprocessed += check.Progress.Unprocessed
+		default:
+			p.Total += j.Chunks[i].Addresses
+			p.Unprocessed += j.Chunks[i].Addresses
+		}
+	}
+	if resp.Stats.Addresses > 0 {
+		resp.Stats.Sendex = sendex / float64(resp.Stats.Addresses)
+	}
+
+	switch {
+	case count[BatchFailed] > 0:
+		resp.Status = BatchFailed
+	case count[BatchCompleted] == len(checks):
+		resp.Status = BatchCompleted
+	case count[BatchStarting] == len(checks):
+		resp.Status = BatchStarting
+	default:
+		resp.Status = BatchProcessing
+	}
+	return resp
+}
+
+// Wait polls the status of the chunks until all of them are completed, see WaitForBatch.
+// A *BatchFailedError of the first failed chunk is returned as soon as one fails.
+func (j *LargeBatchJob) Wait(ctx context.Context, opts ...WaitForBatchOption) (*LargeBatchCheckResponse, error) {
+	options := newWaitForBatchOptions(opts)
+
+	interval := options.interval
+	for {
+		resp, err := j.Check(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.Status {
+		case BatchCompleted:
+			return resp, nil
+		case BatchFailed:
+			for _, check := range resp.Chunks {
+				if check.Status == BatchFailed {
+					return resp, &BatchFailedError{Response: check}
+				}
+			}
+		case BatchProcessing:
+			if options.onProgress != nil {
+				options.onProgress(resp.Progress)
+			}
+		}
+
+		if !sleep(ctx, interval) {
+			return resp, fmt.Errorf("waiting for large batch: %w", ctx.Err())
+		}
+		interval = options.next(interval)
+	}
+}
+
+// Download streams the results of every chunk, in order, as a single file.
+// All the chunks must be completed, the rows are numbered across chunks.
+func (j *LargeBatchJob) Download(ctx context.Context) (*BatchResults, error) {
+	j.mu.Lock()
+	checks := append([]*VerifyBatchCheckResponse{}, j.last...)
+	j.mu.Unlock()
+
+	for i, chunk := range j.Chunks {
+		if checks[i] == nil || checks[i].Status != BatchCompleted {
+			return nil, fmt.Errorf("part %d, batch %d is not completed, see Wait", chunk.Part, chunk.ID)
+		}
+	}
+
+	merged := &mergedResults{ctx: ctx, client: j.client, checks: checks}
+	return &BatchResults{
+		next:   merged.next,
+		closer: merged,
+	}, nil
+}
+
+// mergedResults downloads the results of the chunks one after another
+type mergedResults struct {
+	ctx     context.Context
+	client  largeBatchClient
+	checks  []*VerifyBatchCheckResponse
+	current *BatchResults
+	row     int
+}
+
+func (m *mergedResults) next() (*BatchResult, error) {
+	for {
+		if m.current == nil {
+			if len(m.checks) == 0 {
+				return nil, io.EOF
+			}
+			results, err := m.client.DownloadBatchResults(m.ctx, m.checks[0])
+			if err != nil {
+				return nil, fmt.Errorf("downloading batch %d: %w", m.checks[0].ID, err)
+			}
+			m.current, m.checks = results, m.checks[1:]
+		}
+
+		if m.current.Next() {
+			m.row++
+			result := m.current.Result()
+			result.Row = m.row
+			return result, nil
+		}
+
+		err := m.current.Err()
+		m.current.Close()
+		m.current = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close closes the results being read
+func (m *mergedResults) Close() error {
+	if m.current == nil {
+		return nil
+	}
+	err := m.current.Close()
+	m.current = nil
+	return err
+}
@@ -0,0 +1,132 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func largeBatchEmails(n int) []string {
+	emails := make([]string, n)
+	for i := range emails {
+		emails[i] = fmt.Sprintf("user%d@example.com", i+1)
+	}
+	emails[4] = "undeliverable@example.com"
+	return emails
+}
+
+func TestSubmitLargeBatch(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	sandbox := kickbox.NewSandbox(kickbox.SandboxBatchSteps(1))
+	job, err := sandbox.SubmitLargeBatch(context.TODO(), kickbox.BatchInputFromSlice(largeBatchEmails(10)),
+		kickbox.ChunkSize(4),
+		kickbox.SubmitConcurrency(3),
+		kickbox.WithBatchOptions(kickbox.Filename("export")),
+	)
+	assert.Nil(t, err)
+	assert.Len(t, job.Chunks, 3)
+	for i, chunk := range job.Chunks {
+		assert.Equal(t, i+1, chunk.Part)
+	}
+	assert.Equal(t, []int{4, 4, 2}, []int{job.Chunks[0].Addresses, job.Chunks[1].Addresses, job.Chunks[2].Addresses})
+	assert.ElementsMatch(t, []int{123456, 123457, 123458}, job.IDs())
+
+	// the spooled chunks are removed
+	files, err := os.ReadDir(tmp)
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+
+	// results are not available yet
+	_, err = job.Download(context.TODO())
+	assert.EqualError(t, err, fmt.Sprintf("part 1, batch %d is not completed, see Wait", job.Chunks[0].ID))
+
+	check, err := job.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchStarting, check.Status)
+	assert.Equal(t, kickbox.BatchProgress{Total: 10, Unprocessed: 10}, check.Progress)
+
+	var progress []kickbox.BatchProgress
+	check, err = job.Wait(context.TODO(),
+		kickbox.PollInterval(time.Millisecond),
+		kickbox.OnBatchProgress(func(p kickbox.BatchProgress) { progress = append(progress, p) }),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchCompleted, check.Status)
+	assert.Equal(t, kickbox.BatchStats{Deliverable: 9, Undeliverable: 1, Sendex: 0.9, Addresses: 10}, check.Stats)
+	assert.Equal(t, kickbox.BatchProgress{Deliverable: 9, Undeliverable: 1, Total: 10}, check.Progress)
+	assert.Equal(t, []kickbox.BatchProgress{{Deliverable: 4, Undeliverable: 1, Total: 10, Unprocessed: 5}}, progress)
+	assert.Equal(t, "export (part 2)", check.Chunks[1].Name)
+
+	results, err := job.Download(context.TODO())
+	assert.Nil(t, err)
+	defer results.Close()
+
+	var emails []string
+	for results.Next() {
+		r := results.Result()
+		assert.Equal(t, len(emails)+1, r.Row)
+		emails = append(emails, r.Email)
+	}
+	assert.Nil(t, results.Err())
+	assert.Equal(t, largeBatchEmails(10), emails)
+}
+
+func TestLargeBatchJobFromChunks(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	sandbox := kickbox.NewSandbox()
+	submitted, err := sandbox.SubmitLargeBatch(context.TODO(), kickbox.BatchInputFromSlice(largeBatchEmails(10)), kickbox.ChunkSize(4))
+	assert.Nil(t, err)
+
+	// rebuilt from the persisted chunks, in any order
+	chunks := submitted.Chunks
+	job := sandbox.LargeBatchJob(chunks[2], chunks[0], chunks[1])
+	assert.Equal(t, chunks, job.Chunks)
+
+	check, err := job.Wait(context.TODO(), kickbox.PollInterval(time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchStats{Deliverable: 9, Undeliverable: 1, Sendex: 0.9, Addresses: 10}, check.Stats)
+
+	results, err := job.Download(context.TODO())
+	assert.Nil(t, err)
+	defer results.Close()
+
+	var emails []string
+	for results.Next() {
+		emails = append(emails, results.Result().Email)
+	}
+	assert.Nil(t, results.Err())
+	assert.Equal(t, largeBatchEmails(10), emails)
+}
+
+func TestSubmitLargeBatchErrors(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	sandbox := kickbox.NewSandbox(kickbox.PrependSandboxRules(kickbox.SandboxRule{
+		Match: kickbox.MatchLocalPart("user7"),
+		Err:   &kickbox.APIError{HTTPStatus: http.StatusServiceUnavailable},
+	}))
+
+	// the chunks submitted before the error are returned
+	job, err := sandbox.SubmitLargeBatch(context.TODO(), kickbox.BatchInputFromSlice(largeBatchEmails(12)),
+		kickbox.ChunkSize(5),
+		kickbox.SubmitConcurrency(1),
+	)
+	assert.True(t, errors.Is(err, kickbox.ErrServerError))
+	assert.Contains(t, err.Error(), "submitting part 2: ")
+	assert.Equal(t, []kickbox.LargeBatchChunk{{Part: 1, ID: 123456, Addresses: 5}}, job.Chunks)
+
+	job, err = sandbox.SubmitLargeBatch(context.TODO(), kickbox.BatchInputFromSlice([]string{"", " "}))
+	assert.EqualError(t, err, "no email addresses found")
+	assert.Nil(t, job)
+}
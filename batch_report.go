@@ -0,0 +1,83 @@
+package kickbox
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// batchResultsHeader is the header of the results file served by BatchReport
+var batchResultsHeader = []string{"email", "result", "reason", "role", "free", "disposable", "accept_all", "did_you_mean", "sendex", "user", "domain"}
+
+// BatchReport reports the results of a simulated batch job the way kickbox does: the
+// progress and stats of the status checks and the results file. It is shared by the
+// ClientSandbox and the kickboxtest server, each one deciding when the results are processed.
+type BatchReport []ResponseVerify
+
+// Progress tallies the first processed results, while the job is processing
+func (r BatchReport) Progress(processed int) BatchProgress {
+	if processed > len(r) {
+		processed = len(r)
+	}
+
+	progress := BatchProgress{
+		Total:       len(r),
+		Unprocessed: len(r) - processed,
+	}
+	for _, result := range r[:processed] {
+		switch result.Result {
+		case ResultDeliverable:
+			progress.Deliverable++
+		case ResultUndeliverable:
+			progress.Undeliverable++
+		case ResultRisky:
+			progress.Risky++
+		default:
+			progress.Unknown++
+		}
+	}
+	return progress
+}
+
+// Stats tallies every result once the job is completed, the Sendex is their average
+func (r BatchReport) Stats() BatchStats {
+	progress := r.Progress(len(r))
+	stats := BatchStats{
+		Deliverable:   progress.Deliverable,
+		Undeliverable: progress.Undeliverable,
+		Risky:         progress.Risky,
+		Unknown:       progress.Unknown,
+		Addresses:     len(r),
+	}
+	if len(r) == 0 {
+		return stats
+	}
+	for _, result := range r {
+		stats.Sendex += result.Sendex
+	}
+	stats.Sendex /= float64(len(r))
+	return stats
+}
+
+// WriteCSV writes the results file of the job, as read by ParseBatchResults
+func (r BatchReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(batchResultsHeader)
+	for _, result := range r {
+		_ = cw.Write([]string{
+			result.Email,
+			result.Result.String(),
+			result.Reason.String(),
+			strconv.FormatBool(result.Role),
+			strconv.FormatBool(result.Free),
+			strconv.FormatBool(result.Disposable),
+			strconv.FormatBool(result.AcceptAll),
+			result.DidYouMean,
+			strconv.FormatFloat(result.Sendex, 'f', -1, 64),
+			result.User,
+			result.Domain,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,39 @@
+package kickbox_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchReport(t *testing.T) {
+	report := kickbox.BatchReport{
+		{Email: "a@example.com", Result: kickbox.ResultDeliverable, Reason: kickbox.ReasonAcceptedEmail, Sendex: 1, User: "a", Domain: "example.com", Success: true},
+		{Email: "b@example.com", Result: kickbox.ResultUndeliverable, Reason: kickbox.ReasonRejectedEmail, Sendex: 0.2, User: "b", Domain: "example.com", Success: true},
+		{Email: "c@example.com", Result: kickbox.ResultRisky, Reason: kickbox.ReasonLowQuality, AcceptAll: true, Sendex: 0.3, User: "c", Domain: "example.com", Success: true},
+		{Email: "d@example.com", Result: kickbox.ResultUnknown, Reason: kickbox.ReasonTimeout, User: "d", Domain: "example.com", Success: true},
+	}
+
+	assert.Equal(t, kickbox.BatchProgress{Deliverable: 1, Undeliverable: 1, Total: 4, Unprocessed: 2}, report.Progress(2))
+	assert.Equal(t, kickbox.BatchProgress{Deliverable: 1, Undeliverable: 1, Risky: 1, Unknown: 1, Total: 4}, report.Progress(10))
+	assert.Equal(t, kickbox.BatchStats{Deliverable: 1, Undeliverable: 1, Risky: 1, Unknown: 1, Sendex: 0.375, Addresses: 4}, report.Stats())
+	assert.Equal(t, kickbox.BatchStats{}, kickbox.BatchReport{}.Stats())
+
+	// read back as downloaded
+	var buf bytes.Buffer
+	assert.Nil(t, report.WriteCSV(&buf))
+	results, err := kickbox.ParseBatchResults(io.NopCloser(&buf))
+	assert.Nil(t, err)
+	defer results.Close()
+
+	var parsed kickbox.BatchReport
+	for results.Next() {
+		parsed = append(parsed, results.Result().ResponseVerify)
+	}
+	assert.Nil(t, results.Err())
+	assert.Equal(t, report, parsed)
+}
@@ -0,0 +1,184 @@
+package kickbox
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BatchResult is a row of the results file of a completed batch job
+type BatchResult struct {
+	ResponseVerify
+	Row     int               // row number, starting at 1, the header excluded
+	Columns map[string]string // every column of the row keyed by its header
+}
+
+// BatchResults iterates over the rows of a batch results file without loading it in memory
+//
+//	results, err := client.DownloadBatchResults(ctx, check)
+//	...
+//	defer results.Close()
+//	for results.Next() {
+//		r := results.Result()
+//	}
+//	if err := results.Err(); err != nil {
+//	...
+type BatchResults struct {
+	next    func() (*BatchResult, error)
+	closer  io.Closer
+	current *BatchResult
+	err     error
+}
+
+// Next advances to the next result, returns false when there are no more results or on error
+func (r *BatchResults) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	r.current, r.err = r.next()
+	if r.err == io.EOF {
+		r.err = nil
+		r.current = nil
+	}
+	return r.current != nil
+}
+
+// Result returns the current result
+func (r *BatchResults) Result() *BatchResult {
+	return r.current
+}
+
+// Err returns the error found while iterating, if any
+func (r *BatchResults) Err() error {
+	return r.err
+}
+
+// Close releases the underlying reader
+func (r *BatchResults) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// ParseBatchResults reads the CSV results of a batch job. Columns are matched by
+// header name to the ResponseVerify fields, all of them are kept in Columns.
+func ParseBatchResults(rc io.ReadCloser) (*BatchResults, error) {
+	reader := csv.NewReader(rc)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		rc.Close()
+		if err == io.EOF {
+			return nil, errors.New("reading results header: empty file")
+		}
+		return nil, fmt.Errorf("reading results header: %v", err)
+	}
+
+	fields := make([]string, len(header))
+	for i, name := range header {
+		fields[i] = batchResultField(name)
+	}
+
+	row := 0
+	next := func() (*BatchResult, error) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading results row %d: %v", row+1, err)
+		}
+		row++
+
+		result := BatchResult{
+			Row:     row,
+			Columns: make(map[string]string, len(record)),
+		}
+		result.Success = true
+		for i, value := range record {
+			if i >= len(header) {
+				break
+			}
+			result.Columns[header[i]] = value
+			if err := result.set(fields[i], value); err != nil {
+				return nil, fmt.Errorf("reading results row %d: column %q: %v", row, header[i], err)
+			}
+		}
+		// files without an email header have the address in the first column
+		if result.Email == "" && len(record) > 0 && strings.Contains(record[0], "@") {
+			result.Email = record[0]
+		}
+
+		return &result, nil
+	}
+
+	return &BatchResults{
+		next:   next,
+		closer: rc,
+	}, nil
+}
+
+// batchResultField normalizes a header name, e.g. "Accept All" -> "accept_all"
+func batchResultField(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+	switch name {
+	case "email_address", "address":
+		return "email"
+	case "acceptall":
+		return "accept_all"
+	case "didyoumean":
+		return "did_you_mean"
+	}
+	return name
+}
+
+// set assigns the value to the ResponseVerify field matching the header
+func (r *BatchResult) set(field, value string) error {
+	var err error
+	switch field {
+	case "email":
+		r.Email = value
+	case "result":
+		r.Result = Result(value)
+	case "reason":
+		r.Reason = Reason(value)
+	case "role":
+		r.Role, err = parseCSVBool(value)
+	case "free":
+		r.Free, err = parseCSVBool(value)
+	case "disposable":
+		r.Disposable, err = parseCSVBool(value)
+	case "accept_all":
+		r.AcceptAll, err = parseCSVBool(value)
+	case "did_you_mean":
+		r.DidYouMean = value
+	case "sendex":
+		if value != "" {
+			r.Sendex, err = strconv.ParseFloat(value, 64)
+		}
+	case "user":
+		r.User = value
+	case "domain":
+		r.Domain = value
+	}
+	return err
+}
+
+// parseCSVBool accepts the usual boolean representations, empty is false
+func parseCSVBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "f", "0", "no", "n":
+		return false, nil
+	case "true", "t", "1", "yes", "y":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid boolean: %q", value)
+}
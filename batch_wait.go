@@ -0,0 +1,136 @@
+package kickbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBatchFailed is matched by errors.Is when a batch job ends with status "failed"
+var ErrBatchFailed = errors.New("batch failed")
+
+// BatchFailedError is returned by WaitForBatch when the batch job fails
+type BatchFailedError struct {
+	Response *VerifyBatchCheckResponse
+}
+
+// Error implements the error interface
+func (e *BatchFailedError) Error() string {
+	if e.Response.Error == "" {
+		return fmt.Sprintf("batch %d failed", e.Response.ID)
+	}
+	return fmt.Sprintf("batch %d failed: %s", e.Response.ID, e.Response.Error)
+}
+
+// Is matches ErrBatchFailed
+func (e *BatchFailedError) Is(target error) bool {
+	return target == ErrBatchFailed
+}
+
+// WaitForBatchRequestOptions holds the optional parameters for WaitForBatch
+type WaitForBatchRequestOptions struct {
+	interval    time.Duration
+	maxInterval time.Duration
+	backoff     float64
+	onProgress  func(BatchProgress)
+}
+
+// WaitForBatchOption option type
+type WaitForBatchOption func(*WaitForBatchRequestOptions)
+
+// PollInterval sets the time between status checks. Default: 5 seconds
+func PollInterval(d time.Duration) WaitForBatchOption {
+	return func(o *WaitForBatchRequestOptions) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// PollBackoff multiplies the poll interval by factor after every check,
+// up to the given maximum interval. Default: no backoff
+func PollBackoff(factor float64, max time.Duration) WaitForBatchOption {
+	return func(o *WaitForBatchRequestOptions) {
+		if factor >= 1 && max > 0 {
+			o.backoff = factor
+			o.maxInterval = max
+		}
+	}
+}
+
+// OnBatchProgress sets a callback receiving the progress on every "processing" status check
+func OnBatchProgress(fn func(BatchProgress)) WaitForBatchOption {
+	return func(o *WaitForBatchRequestOptions) {
+		o.onProgress = fn
+	}
+}
+
+// batchCheckFunc is the signature of Verifier.VerifyBatchCheck
+type batchCheckFunc func(ctx context.Context, batchID string) (*VerifyBatchCheckResponse, error)
+
+// waitForBatch polls the batch status until it is completed or failed
+func waitForBatch(ctx context.Context, check batchCheckFunc, batchID string, opts []WaitForBatchOption) (*VerifyBatchCheckResponse, error) {
+	options := newWaitForBatchOptions(opts)
+
+	interval := options.interval
+	for {
+		resp, err := check(ctx, batchID)
+		if err != nil {
+			return nil, fmt.Errorf("checking batch %s: %w", batchID, err)
+		}
+
+		switch resp.Status {
+		case BatchCompleted:
+			return resp, nil
+		case BatchFailed:
+			return resp, &BatchFailedError{Response: resp}
+		case BatchProcessing:
+			if options.onProgress != nil {
+				options.onProgress(resp.Progress)
+			}
+		}
+
+		if !sleep(ctx, interval) {
+			return resp, fmt.Errorf("waiting for batch %s: %w", batchID, ctx.Err())
+		}
+
+		interval = options.next(interval)
+	}
+}
+
+// newWaitForBatchOptions applies the options over the defaults
+func newWaitForBatchOptions(opts []WaitForBatchOption) WaitForBatchRequestOptions {
+	const defaultPollInterval = 5 * time.Second
+	options := WaitForBatchRequestOptions{
+		interval: defaultPollInterval,
+		backoff:  1,
+	}
+	for _, apply := range opts {
+		apply(&options)
+	}
+	return options
+}
+
+// next returns the poll interval following interval
+func (o *WaitForBatchRequestOptions) next(interval time.Duration) time.Duration {
+	if o.backoff <= 1 {
+		return interval
+	}
+	interval = time.Duration(float64(interval) * o.backoff)
+	if interval > o.maxInterval {
+		interval = o.maxInterval
+	}
+	return interval
+}
+
+// WaitForBatch polls the batch status until the job is completed, returning the final status.
+// A *BatchFailedError is returned when the job fails.
+func (c *ClientHTTP) WaitForBatch(ctx context.Context, batchID string, opts ...WaitForBatchOption) (*VerifyBatchCheckResponse, error) {
+	return waitForBatch(ctx, c.VerifyBatchCheck, batchID, opts)
+}
+
+// WaitForBatch polls the sandbox batch status until the job is completed
+func (c *ClientSandbox) WaitForBatch(ctx context.Context, batchID string, opts ...WaitForBatchOption) (*VerifyBatchCheckResponse, error) {
+	return waitForBatch(ctx, c.VerifyBatchCheck, batchID, opts)
+}
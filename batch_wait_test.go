@@ -0,0 +1,131 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// batchStatusServer replies with the given bodies in sequence, repeating the last one
+func batchStatusServer(bodies ...string) (*httptest.Server, *int32) {
+	var calls int32
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n > len(bodies) {
+			n = len(bodies)
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(bodies[n-1]))
+	}
+	return httptest.NewServer(http.HandlerFunc(handler)), &calls
+}
+
+func TestWaitForBatchCompleted(t *testing.T) {
+	svr, calls := batchStatusServer(
+		`{"id":123,"status":"starting","success":true}`,
+		`{"id":123,"status":"processing","progress":{"deliverable":1,"total":3,"unprocessed":2},"success":true}`,
+		`{"id":123,"status":"processing","progress":{"deliverable":1,"risky":1,"total":3,"unprocessed":1},"success":true}`,
+		`{"id":123,"status":"completed","download_url":"https://download","stats":{"deliverable":2,"risky":1,"sendex":0.8,"addresses":3},"success":true}`,
+	)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey", kickbox.OverrideBaseURL(svr.URL))
+	assert.Nil(t, err)
+
+	var progress []kickbox.BatchProgress
+	resp, err := client.WaitForBatch(context.TODO(), "123",
+		kickbox.PollInterval(time.Millisecond),
+		kickbox.PollBackoff(2, 4*time.Millisecond),
+		kickbox.OnBatchProgress(func(p kickbox.BatchProgress) {
+			progress = append(progress, p)
+		}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(calls))
+	assert.Equal(t, kickbox.BatchCompleted, resp.Status)
+	assert.Equal(t, "https://download", resp.DownloadURL)
+	assert.Equal(t, kickbox.BatchStats{Deliverable: 2, Risky: 1, Sendex: 0.8, Addresses: 3}, resp.Stats)
+	assert.Equal(t, []kickbox.BatchProgress{
+		{Deliverable: 1, Total: 3, Unprocessed: 2},
+		{Deliverable: 1, Risky: 1, Total: 3, Unprocessed: 1},
+	}, progress)
+}
+
+func TestWaitForBatchFailed(t *testing.T) {
+	svr, _ := batchStatusServer(
+		`{"id":123,"status":"processing","success":true}`,
+		`{"id":123,"status":"failed","error":"Description of error here...","success":true}`,
+	)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey", kickbox.OverrideBaseURL(svr.URL))
+	assert.Nil(t, err)
+
+	resp, err := client.WaitForBatch(context.TODO(), "123", kickbox.PollInterval(time.Millisecond))
+	assert.Equal(t, kickbox.BatchFailed, resp.Status)
+	assert.True(t, errors.Is(err, kickbox.ErrBatchFailed))
+	assert.EqualError(t, err, "batch 123 failed: Description of error here...")
+
+	var failedErr *kickbox.BatchFailedError
+	assert.True(t, errors.As(err, &failedErr))
+	assert.Equal(t, resp, failedErr.Response)
+}
+
+func TestWaitForBatchContextCanceled(t *testing.T) {
+	svr, _ := batchStatusServer(`{"id":123,"status":"processing","success":true}`)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey", kickbox.OverrideBaseURL(svr.URL))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	resp, err := client.WaitForBatch(ctx, "123", kickbox.PollInterval(time.Second))
+	assert.Equal(t, kickbox.BatchProcessing, resp.Status)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.EqualError(t, err, "waiting for batch 123: context deadline exceeded")
+}
+
+func TestWaitForBatchCheckError(t *testing.T) {
+	client, err := kickbox.New("apikey")
+	assert.Nil(t, err)
+
+	_, err = client.WaitForBatch(context.TODO(), "")
+	assert.EqualError(t, err, "checking batch : batch id is empty")
+}
+
+func TestWaitForBatchSandbox(t *testing.T) {
+	client := kickbox.NewSandbox(kickbox.SandboxBatchSteps(2))
+
+	emailsFile, err := os.Open("./testdata/sample.csv")
+	assert.Nil(t, err)
+
+	batch, err := client.VerifyBatch(context.TODO(), emailsFile)
+	assert.Nil(t, err)
+
+	var progress []kickbox.BatchProgress
+	resp, err := client.WaitForBatch(context.TODO(), strconv.Itoa(batch.ID),
+		kickbox.PollInterval(time.Millisecond),
+		kickbox.OnBatchProgress(func(p kickbox.BatchProgress) {
+			progress = append(progress, p)
+		}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, batch.ID, resp.ID)
+	assert.Equal(t, kickbox.BatchCompleted, resp.Status)
+	assert.Len(t, progress, 2)
+
+	_, err = client.WaitForBatch(context.TODO(), "1")
+	assert.EqualError(t, err, "checking batch 1: (sandbox) batch not found: 1")
+}
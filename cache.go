@@ -0,0 +1,127 @@
+package kickbox
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// CacheEntry is a cached verification result
+type CacheEntry struct {
+	Headers   ResponseVerifyHeaders `json:"headers"`
+	Response  ResponseVerify        `json:"response"`
+	ExpiresAt time.Time             `json:"expires_at"`
+}
+
+// Expired reports whether the entry is expired at now
+func (e *CacheEntry) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+// Cache stores verification results keyed by normalized email.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the entry of key, if found
+	Get(key string) (*CacheEntry, bool)
+	// Set stores the entry of key, replacing the existing one
+	Set(key string, entry CacheEntry) error
+	// Delete removes the entry of key, if found
+	Delete(key string) error
+}
+
+// CacheTTL decides how long a response is cached, zero or negative means not cached
+type CacheTTL func(resp *ResponseVerify) time.Duration
+
+// FixedCacheTTL caches every response for d
+func FixedCacheTTL(d time.Duration) CacheTTL {
+	return func(*ResponseVerify) time.Duration {
+		return d
+	}
+}
+
+// DefaultCacheTTL caches conclusive results for longer than the unknown ones,
+// which are usually caused by timeouts or unavailable mail servers
+func DefaultCacheTTL(resp *ResponseVerify) time.Duration {
+	const day = 24 * time.Hour
+	switch resp.Result {
+	case ResultDeliverable:
+		return 30 * day
+	case ResultUndeliverable, ResultRisky:
+		return 7 * day
+	default:
+		return time.Hour
+	}
+}
+
+// CacheStats holds the cache usage of a CachedVerifier
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// CachedVerifier is a Verifier serving the verification results from a cache.
+// Batch requests are not cached.
+type CachedVerifier struct {
+	Verifier
+	cache  Cache
+	ttl    CacheTTL
+	hits   uint64 // accessed atomically
+	misses uint64 // accessed atomically
+}
+
+// Ensure Verifier implementation
+var _ Verifier = (*CachedVerifier)(nil)
+
+// WithCache decorates the verifier with the cache, the responses are cached as long as ttl returns.
+// DefaultCacheTTL is used when ttl is nil.
+func WithCache(v Verifier, cache Cache, ttl CacheTTL) *CachedVerifier {
+	if ttl == nil {
+		ttl = DefaultCacheTTL
+	}
+	return &CachedVerifier{
+		Verifier: v,
+		cache:    cache,
+		ttl:      ttl,
+	}
+}
+
+// Verify returns the cached response of the email, flagged as Cached in the headers,
+// or verifies it and caches the response. Errors, missing and unsuccessful responses are never cached.
+func (c *CachedVerifier) Verify(ctx context.Context, email string, opts ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error) {
+	key := EmailKey(email)
+
+	if entry, found := c.cache.Get(key); found {
+		if !entry.Expired(time.Now()) {
+			atomic.AddUint64(&c.hits, 1)
+			header, resp := entry.Headers, entry.Response
+			header.Cached = true
+			return &header, &resp, nil
+		}
+		_ = c.cache.Delete(key)
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	header, resp, err := c.Verifier.Verify(ctx, email, opts...)
+	if err != nil || resp == nil || header == nil || !resp.Success {
+		return header, resp, err
+	}
+
+	if ttl := c.ttl(resp); ttl > 0 {
+		// a failing cache must not fail a successful verification
+		_ = c.cache.Set(key, CacheEntry{
+			Headers:   *header,
+			Response:  *resp,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	}
+
+	return header, resp, nil
+}
+
+// Stats returns the cache hits and misses
+func (c *CachedVerifier) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
@@ -0,0 +1,208 @@
+package kickbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFileCacheMaxEntries = 100000
+	// compaction starts when the log has more stale records than this and than live entries
+	fileCacheCompactMinStale = 1000
+)
+
+// ErrCacheClosed is returned when writing to a closed cache
+var ErrCacheClosed = errors.New("cache is closed")
+
+// FileCache is a Cache persisted in a local file, surviving restarts.
+// The entries are held in memory and every change is appended to the file,
+// which is compacted once most of its records are stale.
+type FileCache struct {
+	mu      sync.Mutex
+	log     *appendLog
+	entries *LRUCache
+}
+
+// Ensure Cache implementation
+var _ Cache = (*FileCache)(nil)
+
+// FileCacheOptions holds optional values to parametrize the file cache
+type FileCacheOptions struct {
+	maxEntries int
+}
+
+// FileCacheOption signature
+type FileCacheOption func(*FileCacheOptions) error
+
+// MaxCacheEntries limits the number of entries, the least recently used one is
+// evicted when full. Default: 100000
+func MaxCacheEntries(n int) FileCacheOption {
+	return func(o *FileCacheOptions) error {
+		if n <= 0 {
+			return errors.New("max cache entries must be greater than zero")
+		}
+		o.maxEntries = n
+		return nil
+	}
+}
+
+// fileCacheRecord is a line of the cache file, a nil Entry deletes the key
+type fileCacheRecord struct {
+	Key   string      `json:"key"`
+	Entry *CacheEntry `json:"entry,omitempty"`
+}
+
+// NewFileCache opens the cache stored at path, creating it if it does not exist.
+// The expired entries are dropped while loading. Close must be called to release the file.
+func NewFileCache(path string, opts ...FileCacheOption) (*FileCache, error) {
+	options := FileCacheOptions{
+		maxEntries: defaultFileCacheMaxEntries,
+	}
+	for _, o := range opts {
+		if err := o(&options); err != nil {
+			return nil, fmt.Errorf("applying optional settings: %v", err)
+		}
+	}
+
+	entries, err := NewLRUCache(options.maxEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &FileCache{
+		log:     &appendLog{path: path, name: "cache file", errClosed: ErrCacheClosed},
+		entries: entries,
+	}
+
+	now := time.Now()
+	err = c.log.open(func(data []byte) error {
+		var record fileCacheRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		if record.Entry == nil || record.Entry.Expired(now) {
+			_ = c.entries.Delete(record.Key)
+			return nil
+		}
+		_ = c.entries.Set(record.Key, *record.Entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Get returns the entry of key, expired entries are not returned
+func (c *FileCache) Get(key string) (*CacheEntry, bool) {
+	return c.entries.Get(key)
+}
+
+// Set stores the entry of key
+func (c *FileCache) Set(key string, entry CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.log.append(fileCacheRecord{Key: key, Entry: &entry}); err != nil {
+		return err
+	}
+	_ = c.entries.Set(key, entry)
+	return c.maybeCompact()
+}
+
+// Delete removes the entry of key
+func (c *FileCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, found := c.entries.Get(key); !found {
+		return nil
+	}
+	if err := c.log.append(fileCacheRecord{Key: key}); err != nil {
+		return err
+	}
+	_ = c.entries.Delete(key)
+	return c.maybeCompact()
+}
+
+// Len returns the number of entries
+func (c *FileCache) Len() int {
+	return c.entries.Len()
+}
+
+// Compact rewrites the file with the live entries only, dropping the stale records
+func (c *FileCache) Compact() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.compact()
+}
+
+// Export writes the live entries to w, in the format read by Import
+func (c *FileCache) Export(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	err := c.entries.each(func(key string, entry CacheEntry) error {
+		if entry.Expired(time.Now()) {
+			return nil
+		}
+		return writeRecord(bw, fileCacheRecord{Key: key, Entry: &entry})
+	})
+	if err != nil {
+		return fmt.Errorf("exporting cache: %v", err)
+	}
+	return bw.Flush()
+}
+
+// Import stores the entries read from r, as written by Export. Expired entries are skipped.
+func (c *FileCache) Import(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		var record fileCacheRecord
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("importing cache: %v", err)
+		}
+		if record.Entry == nil || record.Entry.Expired(time.Now()) {
+			continue
+		}
+		if err := c.Set(record.Key, *record.Entry); err != nil {
+			return fmt.Errorf("importing cache: %v", err)
+		}
+	}
+}
+
+// Close flushes the file to disk and releases it
+func (c *FileCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.log.close()
+}
+
+// maybeCompact compacts the file when most of its records are stale. Must be called with the lock held.
+func (c *FileCache) maybeCompact() error {
+	if !c.log.shouldCompact(c.entries.Len(), fileCacheCompactMinStale) {
+		return nil
+	}
+	return c.compact()
+}
+
+// compact rewrites the file with the live entries. Must be called with the lock held.
+func (c *FileCache) compact() error {
+	return c.log.compact(func(write func(record interface{}) error) error {
+		return c.entries.each(func(key string, entry CacheEntry) error {
+			if entry.Expired(time.Now()) {
+				return nil
+			}
+			return write(fileCacheRecord{Key: key, Entry: &entry})
+		})
+	})
+}
@@ -0,0 +1,205 @@
+package kickbox_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cacheEntry(result kickbox.Result, ttl time.Duration) kickbox.CacheEntry {
+	return kickbox.CacheEntry{
+		Headers:   kickbox.ResponseVerifyHeaders{Balance: 1, HTTPStatus: 200},
+		Response:  kickbox.ResponseVerify{Result: result, Success: true},
+		ExpiresAt: time.Now().Add(ttl),
+	}
+}
+
+func TestFileCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	cache, err := kickbox.NewFileCache(path)
+	assert.Nil(t, err)
+	assert.Nil(t, cache.Set("a@example.com", cacheEntry("deliverable", time.Hour)))
+	assert.Nil(t, cache.Set("b@example.com", cacheEntry("risky", time.Hour)))
+	assert.Nil(t, cache.Set("c@example.com", cacheEntry("unknown", time.Millisecond)))
+	assert.Nil(t, cache.Delete("b@example.com"))
+	assert.Nil(t, cache.Close())
+	assert.Equal(t, kickbox.ErrCacheClosed, cache.Set("d@example.com", cacheEntry("deliverable", time.Hour)))
+
+	time.Sleep(5 * time.Millisecond)
+
+	cache, err = kickbox.NewFileCache(path)
+	assert.Nil(t, err)
+	defer cache.Close()
+
+	entry, found := cache.Get("a@example.com")
+	assert.True(t, found)
+	assert.Equal(t, kickbox.ResultDeliverable, entry.Response.Result)
+	assert.Equal(t, 1, entry.Headers.Balance)
+
+	_, found = cache.Get("b@example.com")
+	assert.False(t, found)
+	_, found = cache.Get("c@example.com")
+	assert.False(t, found)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestFileCacheTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	cache, err := kickbox.NewFileCache(path)
+	assert.Nil(t, err)
+	assert.Nil(t, cache.Set("a@example.com", cacheEntry("deliverable", time.Hour)))
+	assert.Nil(t, cache.Close())
+
+	// an interrupted write
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	assert.Nil(t, err)
+	_, _ = f.WriteString(`{"key":"b@example.com","entr`)
+	assert.Nil(t, f.Close())
+
+	cache, err = kickbox.NewFileCache(path)
+	assert.Nil(t, err)
+	assert.Nil(t, cache.Set("c@example.com", cacheEntry("deliverable", time.Hour)))
+	assert.Nil(t, cache.Close())
+
+	cache, err = kickbox.NewFileCache(path)
+	assert.Nil(t, err)
+	defer cache.Close()
+	assert.Equal(t, 2, cache.Len())
+
+	assert.Nil(t, os.WriteFile(path+".broken", []byte("{broken\n{}\n"), 0o600))
+	_, err = kickbox.NewFileCache(path + ".broken")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line 1:")
+}
+
+func TestFileCacheMaxEntries(t *testing.T) {
+	_, err := kickbox.NewFileCache(filepath.Join(t.TempDir(), "cache.jsonl"), kickbox.MaxCacheEntries(0))
+	assert.EqualError(t, err, "applying optional settings: max cache entries must be greater than zero")
+
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	cache, err := kickbox.NewFileCache(path, kickbox.MaxCacheEntries(2))
+	assert.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("%d@example.com", i), cacheEntry("deliverable", time.Hour)))
+	}
+	assert.Equal(t, 2, cache.Len())
+	assert.Nil(t, cache.Close())
+
+	cache, err = kickbox.NewFileCache(path, kickbox.MaxCacheEntries(2))
+	assert.Nil(t, err)
+	defer cache.Close()
+	assert.Equal(t, 2, cache.Len())
+	_, found := cache.Get("4@example.com")
+	assert.True(t, found)
+}
+
+func TestFileCacheCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	cache, err := kickbox.NewFileCache(path)
+	assert.Nil(t, err)
+	defer cache.Close()
+
+	// the same keys overwritten until the stale records trigger the compaction
+	for i := 0; i < 1500; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("%d@example.com", i%10), cacheEntry("deliverable", time.Hour)))
+	}
+	assert.Less(t, lines(t, path), 1500)
+
+	assert.Nil(t, cache.Compact())
+	assert.Equal(t, 10, lines(t, path))
+	assert.Equal(t, 10, cache.Len())
+}
+
+func TestFileCacheAppendAfterCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	cache, err := kickbox.NewFileCache(path)
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("%d@example.com", i), cacheEntry("deliverable", time.Hour)))
+		assert.Nil(t, cache.Compact())
+		assert.Nil(t, cache.Set(fmt.Sprintf("%d@example.com", i), cacheEntry("risky", time.Hour)))
+	}
+	assert.Nil(t, cache.Delete("0@example.com"))
+	assert.Nil(t, cache.Close())
+	assert.Equal(t, 5, lines(t, path))
+
+	// the records appended after the compactions are replayed
+	cache, err = kickbox.NewFileCache(path)
+	assert.Nil(t, err)
+	defer cache.Close()
+	assert.Equal(t, 2, cache.Len())
+	entry, found := cache.Get("2@example.com")
+	assert.True(t, found)
+	assert.Equal(t, kickbox.Result("risky"), entry.Response.Result)
+}
+
+func TestFileCacheExportImport(t *testing.T) {
+	src, err := kickbox.NewFileCache(filepath.Join(t.TempDir(), "src.jsonl"))
+	assert.Nil(t, err)
+	defer src.Close()
+
+	assert.Nil(t, src.Set("a@example.com", cacheEntry("deliverable", time.Hour)))
+	assert.Nil(t, src.Set("b@example.com", cacheEntry("risky", time.Hour)))
+	assert.Nil(t, src.Set("c@example.com", cacheEntry("unknown", -time.Second)))
+
+	var buf bytes.Buffer
+	assert.Nil(t, src.Export(&buf))
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	dst, err := kickbox.NewFileCache(filepath.Join(t.TempDir(), "dst.jsonl"))
+	assert.Nil(t, err)
+	defer dst.Close()
+
+	assert.Nil(t, dst.Import(&buf))
+	entry, found := dst.Get("b@example.com")
+	assert.True(t, found)
+	assert.Equal(t, kickbox.ResultRisky, entry.Response.Result)
+	assert.Equal(t, 2, dst.Len())
+
+	assert.NotNil(t, dst.Import(strings.NewReader("{broken")))
+}
+
+func TestFileCacheConcurrent(t *testing.T) {
+	cache, err := kickbox.NewFileCache(filepath.Join(t.TempDir(), "cache.jsonl"))
+	assert.Nil(t, err)
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("%d@example.com", j)
+				assert.Nil(t, cache.Set(key, cacheEntry("deliverable", time.Hour)))
+				cache.Get(key)
+				if i%2 == 0 {
+					assert.Nil(t, cache.Delete(key))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// lines counts the lines of the file
+func lines(t *testing.T, path string) int {
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	return strings.Count(string(data), "\n")
+}
@@ -0,0 +1,115 @@
+package kickbox
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+// LRUCache is an in-memory Cache holding up to a fixed number of entries,
+// evicting the least recently used one when full
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+// Ensure Cache implementation
+var _ Cache = (*LRUCache)(nil)
+
+// lruItem is the value of the LRUCache list elements
+type lruItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewLRUCache creates an in-memory cache of size entries, size must be greater than zero
+func NewLRUCache(size int) (*LRUCache, error) {
+	if size <= 0 {
+		return nil, errors.New("cache size must be greater than zero")
+	}
+	return &LRUCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}, nil
+}
+
+// Get returns the entry of key, expired entries are removed and not returned
+func (c *LRUCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.items[key]
+	if !found {
+		return nil, false
+	}
+
+	item := elem.Value.(*lruItem)
+	if item.entry.Expired(time.Now()) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	entry := item.entry
+	return &entry, true
+}
+
+// Set stores the entry of key, evicting the least recently used entry when full
+func (c *LRUCache) Set(key string, entry CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, found := c.items[key]; found {
+		elem.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+	}
+	c.items[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	return nil
+}
+
+// Delete removes the entry of key
+func (c *LRUCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, found := c.items[key]; found {
+		c.remove(elem)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including the expired ones not yet removed
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// each calls fn with every entry, from the least to the most recently used
+func (c *LRUCache) each(fn func(key string, entry CacheEntry) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		item := elem.Value.(*lruItem)
+		if err := fn(item.key, item.entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove drops the element. Must be called with the lock held.
+func (c *LRUCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruItem).key)
+}
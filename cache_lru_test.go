@@ -0,0 +1,57 @@
+package kickbox_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	_, err := kickbox.NewLRUCache(0)
+	assert.EqualError(t, err, "cache size must be greater than zero")
+
+	cache, err := kickbox.NewLRUCache(2)
+	assert.Nil(t, err)
+
+	entry := func(result kickbox.Result) kickbox.CacheEntry {
+		return kickbox.CacheEntry{
+			Response:  kickbox.ResponseVerify{Result: result},
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	assert.Nil(t, cache.Set("a", entry("deliverable")))
+	assert.Nil(t, cache.Set("b", entry("risky")))
+
+	// a becomes the most recently used, b is evicted
+	got, found := cache.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, kickbox.ResultDeliverable, got.Response.Result)
+	assert.Nil(t, cache.Set("c", entry("unknown")))
+
+	_, found = cache.Get("b")
+	assert.False(t, found)
+	_, found = cache.Get("c")
+	assert.True(t, found)
+
+	// replacing keeps the size
+	assert.Nil(t, cache.Set("c", entry("undeliverable")))
+	got, _ = cache.Get("c")
+	assert.Equal(t, kickbox.ResultUndeliverable, got.Response.Result)
+	assert.Equal(t, 2, cache.Len())
+
+	assert.Nil(t, cache.Delete("a"))
+	assert.Nil(t, cache.Delete("missing"))
+	_, found = cache.Get("a")
+	assert.False(t, found)
+
+	expired := entry("deliverable")
+	expired.ExpiresAt = time.Now()
+	assert.Nil(t, cache.Set("d", expired))
+	_, found = cache.Get("d")
+	assert.False(t, found)
+	assert.Equal(t, 1, cache.Len())
+}
@@ -0,0 +1,116 @@
+package kickbox_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedVerifier(t *testing.T) {
+	cache, err := kickbox.NewLRUCache(10)
+	assert.Nil(t, err)
+
+	verifier := kickbox.WithCache(kickbox.NewSandbox(), cache, nil)
+
+	header, resp, err := verifier.Verify(context.TODO(), "deliverable@example.com")
+	assert.Nil(t, err)
+	assert.False(t, header.Cached)
+	assert.Equal(t, kickbox.ResultDeliverable, resp.Result)
+
+	header, resp, err = verifier.Verify(context.TODO(), " Deliverable@Example.com")
+	assert.Nil(t, err)
+	assert.True(t, header.Cached)
+	assert.Equal(t, http.StatusOK, header.HTTPStatus)
+	assert.Equal(t, kickbox.ResultDeliverable, resp.Result)
+
+	// unsuccessful responses are not cached
+	_, resp, err = verifier.Verify(context.TODO(), "insufficient-balance@example.com")
+	assert.Nil(t, err)
+	assert.False(t, resp.Success)
+	header, _, err = verifier.Verify(context.TODO(), "insufficient-balance@example.com")
+	assert.Nil(t, err)
+	assert.False(t, header.Cached)
+
+	assert.Equal(t, kickbox.CacheStats{Hits: 1, Misses: 3}, verifier.Stats())
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestCachedVerifierTTL(t *testing.T) {
+	cache, err := kickbox.NewLRUCache(10)
+	assert.Nil(t, err)
+
+	verifier := kickbox.WithCache(kickbox.NewSandbox(), cache, func(resp *kickbox.ResponseVerify) time.Duration {
+		if resp.Result == "unknown" {
+			return 0
+		}
+		return time.Hour
+	})
+
+	_, _, err = verifier.Verify(context.TODO(), "timeout@example.com")
+	assert.Nil(t, err)
+	_, found := cache.Get("timeout@example.com")
+	assert.False(t, found)
+
+	_, _, err = verifier.Verify(context.TODO(), "role@example.com")
+	assert.Nil(t, err)
+	entry, found := cache.Get("role@example.com")
+	assert.True(t, found)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), entry.ExpiresAt, time.Minute)
+
+	// expired entries are verified again
+	entry.ExpiresAt = time.Now().Add(-time.Second)
+	assert.Nil(t, cache.Set("role@example.com", *entry))
+	header, _, err := verifier.Verify(context.TODO(), "role@example.com")
+	assert.Nil(t, err)
+	assert.False(t, header.Cached)
+	assert.Equal(t, kickbox.CacheStats{Hits: 0, Misses: 3}, verifier.Stats())
+}
+
+func TestCachedVerifierNilResponse(t *testing.T) {
+	cache, err := kickbox.NewLRUCache(10)
+	assert.Nil(t, err)
+
+	verifier := kickbox.WithCache(nilVerifier{}, cache, nil)
+
+	header, resp, err := verifier.Verify(context.TODO(), "user@example.com")
+	assert.Nil(t, header)
+	assert.Nil(t, resp)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestDefaultCacheTTL(t *testing.T) {
+	assert.Equal(t, 30*24*time.Hour, kickbox.DefaultCacheTTL(&kickbox.ResponseVerify{Result: "deliverable"}))
+	assert.Equal(t, 7*24*time.Hour, kickbox.DefaultCacheTTL(&kickbox.ResponseVerify{Result: "risky"}))
+	assert.Equal(t, time.Hour, kickbox.DefaultCacheTTL(&kickbox.ResponseVerify{Result: "unknown"}))
+	assert.Equal(t, time.Minute, kickbox.FixedCacheTTL(time.Minute)(&kickbox.ResponseVerify{}))
+}
+
+func TestCachedVerifierConcurrent(t *testing.T) {
+	cache, err := kickbox.NewLRUCache(2)
+	assert.Nil(t, err)
+
+	verifier := kickbox.WithCache(kickbox.NewSandbox(), cache, nil)
+	emails := []string{"deliverable@example.com", "risky@example.com", "role@example.com"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := verifier.Verify(context.TODO(), emails[i%len(emails)])
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	stats := verifier.Stats()
+	assert.Equal(t, uint64(50), stats.Hits+stats.Misses)
+	assert.Equal(t, 2, cache.Len())
+}
@@ -0,0 +1,145 @@
+package kickbox
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// CallbackFunc handles the payload posted by kickbox when a batch job finishes.
+// Returning an error makes the handler reply with a 500 so kickbox retries the callback.
+type CallbackFunc func(ctx context.Context, resp *VerifyBatchCheckResponse) error
+
+// CallbackHandlerOptions holds the optional parameters of the callback handler
+type CallbackHandlerOptions struct {
+	requireSecret    bool
+	secretParam      string
+	secret           string
+	requireSignature bool
+	signatureHeader  string
+	signatureKey     []byte
+	maxBodySize      int64
+}
+
+// CallbackHandlerOption option type
+type CallbackHandlerOption func(*CallbackHandlerOptions)
+
+// CallbackSecret requires the request to carry a shared secret in the given query parameter.
+// The secret must be part of the url set with Callback, e.g.
+// Callback("https://example.com/kickbox?token=s3cr3t") and CallbackSecret("token", "s3cr3t").
+// An empty param or secret, e.g. read from an unset variable, denies every request.
+func CallbackSecret(param, secret string) CallbackHandlerOption {
+	return func(o *CallbackHandlerOptions) {
+		o.requireSecret = true
+		o.secretParam = param
+		o.secret = secret
+	}
+}
+
+// CallbackSignature requires the request to carry, in the given header,
+// the hex encoded HMAC-SHA256 signature of the body. An empty header or key denies every request.
+func CallbackSignature(header string, key []byte) CallbackHandlerOption {
+	return func(o *CallbackHandlerOptions) {
+		o.requireSignature = true
+		o.signatureHeader = header
+		o.signatureKey = key
+	}
+}
+
+// CallbackMaxBodySize limits the size of the payload. Default: 1MB
+func CallbackMaxBodySize(n int64) CallbackHandlerOption {
+	return func(o *CallbackHandlerOptions) {
+		if n > 0 {
+			o.maxBodySize = n
+		}
+	}
+}
+
+// CallbackHandler receives the batch verification callback sent by kickbox to the url set with Callback.
+// The payload is decoded into the same structure VerifyBatchCheck returns.
+// see: https://docs.kickbox.com/docs/batch-verification-api#the-batch-verification-callback
+func CallbackHandler(fn CallbackFunc, opts ...CallbackHandlerOption) http.Handler {
+	const defaultMaxBodySize = 1 << 20
+	options := CallbackHandlerOptions{
+		maxBodySize: defaultMaxBodySize,
+	}
+	for _, apply := range opts {
+		apply(&options)
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			rw.Header().Set("Allow", http.MethodPost)
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			http.Error(rw, "content type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if options.requireSecret && !validSecret(r, options.secretParam, options.secret) {
+			http.Error(rw, "invalid secret", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, options.maxBodySize+1))
+		if err != nil {
+			http.Error(rw, "reading body", http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > options.maxBodySize {
+			http.Error(rw, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if options.requireSignature && !validSignature(body, r.Header.Get(options.signatureHeader), options.signatureKey) {
+			http.Error(rw, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var payload VerifyBatchCheckResponse
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(rw, "decoding body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := fn(r.Context(), &payload); err != nil {
+			http.Error(rw, "handling callback", http.StatusInternalServerError)
+			return
+		}
+
+		rw.WriteHeader(http.StatusOK)
+	})
+}
+
+// validSecret checks the secret carried in the query param, an empty secret matches nothing
+func validSecret(r *http.Request, param, secret string) bool {
+	if param == "" || secret == "" {
+		return false
+	}
+	given := r.URL.Query().Get(param)
+	return subtle.ConstantTimeCompare([]byte(given), []byte(secret)) == 1
+}
+
+// validSignature checks the hex encoded HMAC-SHA256 signature of the body, an empty key matches nothing
+func validSignature(body []byte, signature string, key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(body)
+	return hmac.Equal(given, mac.Sum(nil))
+}
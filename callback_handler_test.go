@@ -0,0 +1,191 @@
+package kickbox_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const callbackPayload = `{
+	"id": 123,
+	"name": "Batch API Process - 05-12-2018-01-58-08",
+	"download_url": "https://{{DOWNLOAD_URL_HERE}}",
+	"created_at": "2018-05-12T18:58:08.000Z",
+	"status": "completed",
+	"stats": {
+	  "deliverable": 2,
+	  "undeliverable": 1,
+	  "risky": 0,
+	  "unknown": 0,
+	  "sendex": 0.35,
+	  "addresses": 3
+	},
+	"error": null,
+	"duration": 0,
+	"success": true,
+	"message": null
+}`
+
+func TestCallbackHandler(t *testing.T) {
+	var received *kickbox.VerifyBatchCheckResponse
+	handler := kickbox.CallbackHandler(func(_ context.Context, resp *kickbox.VerifyBatchCheckResponse) error {
+		received = resp
+		return nil
+	})
+
+	svr := httptest.NewServer(handler)
+	defer svr.Close()
+
+	resp, err := http.Post(svr.URL, "application/json; charset=utf-8", strings.NewReader(callbackPayload))
+	assert.Nil(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 123, received.ID)
+	assert.Equal(t, kickbox.BatchCompleted, received.Status)
+	assert.Equal(t, "https://{{DOWNLOAD_URL_HERE}}", received.DownloadURL)
+	assert.Equal(t, kickbox.BatchStats{Deliverable: 2, Undeliverable: 1, Sendex: 0.35, Addresses: 3}, received.Stats)
+}
+
+func TestCallbackHandlerStatusCodes(t *testing.T) {
+	signatureKey := []byte("signing key")
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, signatureKey)
+		_, _ = mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	fn := func(_ context.Context, resp *kickbox.VerifyBatchCheckResponse) error {
+		if resp.ID == 500 {
+			return errors.New("storage unavailable")
+		}
+		return nil
+	}
+
+	handler := kickbox.CallbackHandler(fn,
+		kickbox.CallbackSecret("token", "s3cr3t"),
+		kickbox.CallbackSignature("X-Signature", signatureKey),
+		kickbox.CallbackMaxBodySize(1024),
+	)
+
+	tests := map[string]struct {
+		method      string
+		target      string
+		contentType string
+		body        string
+		signature   string
+		expected    int
+	}{
+		"ok": {
+			target:   "/?token=s3cr3t",
+			body:     callbackPayload,
+			expected: http.StatusOK,
+		},
+		"wrong method": {
+			method:   http.MethodGet,
+			target:   "/?token=s3cr3t",
+			expected: http.StatusMethodNotAllowed,
+		},
+		"wrong content type": {
+			target:      "/?token=s3cr3t",
+			contentType: "text/plain",
+			body:        callbackPayload,
+			expected:    http.StatusUnsupportedMediaType,
+		},
+		"missing secret": {
+			target:   "/",
+			body:     callbackPayload,
+			expected: http.StatusUnauthorized,
+		},
+		"wrong secret": {
+			target:   "/?token=guess",
+			body:     callbackPayload,
+			expected: http.StatusUnauthorized,
+		},
+		"wrong signature": {
+			target:    "/?token=s3cr3t",
+			body:      callbackPayload,
+			signature: sign("other body"),
+			expected:  http.StatusUnauthorized,
+		},
+		"body too large": {
+			target:   "/?token=s3cr3t",
+			body:     `{"name":"` + strings.Repeat("x", 1024) + `"}`,
+			expected: http.StatusRequestEntityTooLarge,
+		},
+		"broken json": {
+			target:   "/?token=s3cr3t",
+			body:     `{broken json`,
+			expected: http.StatusBadRequest,
+		},
+		"handler error": {
+			target:   "/?token=s3cr3t",
+			body:     `{"id":500,"status":"completed"}`,
+			expected: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tc := range tests {
+		method := http.MethodPost
+		if tc.method != "" {
+			method = tc.method
+		}
+		contentType := "application/json"
+		if tc.contentType != "" {
+			contentType = tc.contentType
+		}
+		signature := sign(tc.body)
+		if tc.signature != "" {
+			signature = tc.signature
+		}
+
+		req := httptest.NewRequest(method, tc.target, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Signature", signature)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, tc.expected, rec.Code, name)
+		if tc.expected == http.StatusMethodNotAllowed {
+			assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestCallbackHandlerEmptySecretDeniesAll(t *testing.T) {
+	called := false
+	fn := func(context.Context, *kickbox.VerifyBatchCheckResponse) error {
+		called = true
+		return nil
+	}
+
+	// e.g. read from an unset environment variable
+	handlers := map[string]http.Handler{
+		"empty secret": kickbox.CallbackHandler(fn, kickbox.CallbackSecret("token", "")),
+		"empty param":  kickbox.CallbackHandler(fn, kickbox.CallbackSecret("", "s3cr3t")),
+		"empty key":    kickbox.CallbackHandler(fn, kickbox.CallbackSignature("X-Signature", nil)),
+		"empty header": kickbox.CallbackHandler(fn, kickbox.CallbackSignature("", []byte("signing key"))),
+	}
+	for name, handler := range handlers {
+		for _, target := range []string{"/", "/?token=", "/?token=s3cr3t"} {
+			req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(callbackPayload))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code, name+" "+target)
+		}
+	}
+	assert.False(t, called)
+}
@@ -0,0 +1,362 @@
+package kickbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the API while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+// Circuit breaker states
+const (
+	CircuitClosed   CircuitState = iota // requests go through
+	CircuitOpen                         // requests fail fast with ErrCircuitOpen
+	CircuitHalfOpen                     // trial requests decide whether to close or open again
+)
+
+// String implements the fmt.Stringer interface
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// circuitWindowBuckets is the number of buckets the failure rate window is split into
+const circuitWindowBuckets = 10
+
+// CircuitBreakerSettings configures a CircuitBreaker. At least one of ConsecutiveFailures
+// or FailureRate must be set.
+type CircuitBreakerSettings struct {
+	// ConsecutiveFailures trips the breaker after this number of failures in a row, 0 disables it
+	ConsecutiveFailures int
+	// FailureRate trips the breaker when the failed requests in the Window reach this ratio (0, 1], 0 disables it
+	FailureRate float64
+	// MinRequests needed in the Window before the FailureRate applies. Default: 10
+	MinRequests int
+	// Window is the rolling period of the FailureRate. Default: 1 minute
+	Window time.Duration
+	// Cooldown is the time the breaker stays open before letting trial requests through. Default: 30 seconds
+	Cooldown time.Duration
+	// HalfOpenRequests is the number of trial requests allowed while half-open,
+	// all of them must succeed to close the breaker. Default: 1
+	HalfOpenRequests int
+	// IsFailure decides which errors count as failures. Default: DefaultRetryable,
+	// rate limiting, server and network errors
+	IsFailure func(error) bool
+	// OnStateChange is called on every state transition, e.g. to fail open while the API is down
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker stops calling the API while it is failing. It opens, failing fast with
+// ErrCircuitOpen, on consecutive failures or a high failure rate. After the cooldown it
+// is half-open: a limited number of trial requests close it on success or open it again.
+// It is safe for concurrent use.
+type CircuitBreaker struct {
+	settings CircuitBreakerSettings
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  uint64 // incremented on every state transition
+	consecutive int    // consecutive failures while closed
+	window      [circuitWindowBuckets]circuitBucket
+	openedAt    time.Time
+	trials      int // trial requests allowed while half-open
+	successes   int // successful trial requests while half-open
+}
+
+// circuitBucket counts the requests of a slice of the failure rate window
+type circuitBucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(settings CircuitBreakerSettings) (*CircuitBreaker, error) {
+	switch {
+	case settings.ConsecutiveFailures < 0:
+		return nil, errors.New("consecutive failures cannot be negative")
+	case settings.FailureRate < 0 || settings.FailureRate > 1:
+		return nil, errors.New("failure rate must be between 0 and 1")
+	case settings.ConsecutiveFailures == 0 && settings.FailureRate == 0:
+		return nil, errors.New("consecutive failures or failure rate must be set")
+	case settings.MinRequests < 0 || settings.HalfOpenRequests < 0:
+		return nil, errors.New("request counts cannot be negative")
+	case settings.Window < 0 || settings.Cooldown < 0:
+		return nil, errors.New("durations cannot be negative")
+	}
+
+	if settings.MinRequests == 0 {
+		settings.MinRequests = 10
+	}
+	if settings.Window == 0 {
+		settings.Window = time.Minute
+	}
+	if settings.Cooldown == 0 {
+		settings.Cooldown = 30 * time.Second
+	}
+	if settings.HalfOpenRequests == 0 {
+		settings.HalfOpenRequests = 1
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = DefaultRetryable
+	}
+
+	return &CircuitBreaker{settings: settings}, nil
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.settings.Cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Allow reports whether a request can go through, ErrCircuitOpen if not. Every allowed
+// request must be followed by a call to Record with the returned generation and its outcome.
+func (b *CircuitBreaker) Allow() (generation uint64, err error) {
+	b.mu.Lock()
+	from := b.state
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.settings.Cooldown {
+			b.mu.Unlock()
+			return 0, ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+		b.trials = 1
+	case CircuitHalfOpen:
+		if b.trials >= b.settings.HalfOpenRequests {
+			b.mu.Unlock()
+			return 0, ErrCircuitOpen
+		}
+		b.trials++
+	}
+
+	to := b.state
+	generation = b.generation
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return generation, nil
+}
+
+// rejecting reports whether Allow would fail now, without taking a trial request
+func (b *CircuitBreaker) rejecting() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		return time.Since(b.openedAt) < b.settings.Cooldown
+	case CircuitHalfOpen:
+		return b.trials >= b.settings.HalfOpenRequests
+	}
+	return false
+}
+
+// Record updates the breaker with the outcome of a request allowed in the generation.
+// Outcomes of an earlier generation are ignored, e.g. a slow request allowed while
+// closed finishing once half-open is not taken as a trial.
+// Canceled requests do not count, neither as failures nor as successes.
+func (b *CircuitBreaker) Record(generation uint64, err error) {
+	canceled := errors.Is(err, context.Canceled)
+	failed := err != nil && !canceled && b.settings.IsFailure(err)
+	now := time.Now()
+
+	b.mu.Lock()
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	from := b.state
+
+	switch b.state {
+	case CircuitHalfOpen:
+		switch {
+		case canceled:
+			// frees the trial slot
+			b.trials--
+		case failed:
+			b.open(now)
+		default:
+			b.successes++
+			if b.successes >= b.settings.HalfOpenRequests {
+				b.setState(CircuitClosed)
+			}
+		}
+	case CircuitClosed:
+		if canceled {
+			break
+		}
+		b.count(now, failed)
+		if b.tripped(now) {
+			b.open(now)
+		}
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// Middleware guards the verifier with the breaker, see CircuitBreakerMiddleware
+func (b *CircuitBreaker) Middleware() Middleware {
+	return func(next Verifier) Verifier {
+		return &circuitBreakerVerifier{Verifier: next, breaker: b}
+	}
+}
+
+// count adds the request to the consecutive failures and the window. Must be called with the lock held.
+func (b *CircuitBreaker) count(now time.Time, failed bool) {
+	if failed {
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+
+	width := b.settings.Window / circuitWindowBuckets
+	if width <= 0 {
+		width = 1
+	}
+	start := now.Truncate(width)
+	bucket := &b.window[int(now.UnixNano()/int64(width))%circuitWindowBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = circuitBucket{start: start}
+	}
+	bucket.total++
+	if failed {
+		bucket.failures++
+	}
+}
+
+// tripped reports whether the failures must open the breaker. Must be called with the lock held.
+func (b *CircuitBreaker) tripped(now time.Time) bool {
+	if b.settings.ConsecutiveFailures > 0 && b.consecutive >= b.settings.ConsecutiveFailures {
+		return true
+	}
+	if b.settings.FailureRate == 0 {
+		return false
+	}
+
+	total, failures := 0, 0
+	for _, bucket := range b.window {
+		if now.Sub(bucket.start) < b.settings.Window {
+			total += bucket.total
+			failures += bucket.failures
+		}
+	}
+	return total >= b.settings.MinRequests && float64(failures)/float64(total) >= b.settings.FailureRate
+}
+
+// open opens the breaker. Must be called with the lock held.
+func (b *CircuitBreaker) open(now time.Time) {
+	b.setState(CircuitOpen)
+	b.openedAt = now
+}
+
+// setState moves to the state resetting the counters, the outcomes of the requests
+// allowed before are ignored. Must be called with the lock held.
+func (b *CircuitBreaker) setState(state CircuitState) {
+	b.state = state
+	b.generation++
+	b.consecutive = 0
+	b.window = [circuitWindowBuckets]circuitBucket{}
+	b.trials = 0
+	b.successes = 0
+}
+
+// notify calls the state change callback, without the lock held
+func (b *CircuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, to)
+	}
+}
+
+// WithCircuitBreaker guards every request of the client with the breaker.
+// The breaker can be shared between clients.
+func WithCircuitBreaker(b *CircuitBreaker) ClientHTTPOption {
+	return func(o *ClientHTTPOptions) error {
+		if b == nil {
+			return errors.New("circuit breaker is nil")
+		}
+		o.circuitBreaker = b
+		return nil
+	}
+}
+
+// CircuitBreakerMiddleware fails fast with ErrCircuitOpen for the cooldown after threshold
+// consecutive transient failures (rate limiting, server and network errors).
+// Then a single trial request decides whether the requests go through again.
+// A zero cooldown is the default, 30 seconds. Like the stdlib constructors it panics with
+// a threshold below 1 or a negative cooldown.
+// See NewCircuitBreaker for the failure rate and the rest of settings.
+func CircuitBreakerMiddleware(threshold int, cooldown time.Duration) Middleware {
+	if threshold < 1 {
+		panic("kickbox: CircuitBreakerMiddleware threshold must be greater than zero")
+	}
+	b, err := NewCircuitBreaker(CircuitBreakerSettings{
+		ConsecutiveFailures: threshold,
+		Cooldown:            cooldown,
+	})
+	if err != nil {
+		panic("kickbox: CircuitBreakerMiddleware: " + err.Error())
+	}
+	return b.Middleware()
+}
+
+// circuitBreakerVerifier is the Verifier decorated by a CircuitBreaker
+type circuitBreakerVerifier struct {
+	Verifier
+	breaker *CircuitBreaker
+}
+
+func (c *circuitBreakerVerifier) Verify(ctx context.Context, email string, opts ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error) {
+	generation, err := c.breaker.Allow()
+	if err != nil {
+		return nil, nil, err
+	}
+	header, resp, err := c.Verifier.Verify(ctx, email, opts...)
+	c.breaker.Record(generation, err)
+	return header, resp, err
+}
+
+func (c *circuitBreakerVerifier) VerifyBatch(ctx context.Context, file io.ReadCloser, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
+	generation, err := c.breaker.Allow()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	resp, err := c.Verifier.VerifyBatch(ctx, file, opts...)
+	c.breaker.Record(generation, err)
+	return resp, err
+}
+
+func (c *circuitBreakerVerifier) VerifyBatchCheck(ctx context.Context, batchID string) (*VerifyBatchCheckResponse, error) {
+	generation, err := c.breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Verifier.VerifyBatchCheck(ctx, batchID)
+	c.breaker.Record(generation, err)
+	return resp, err
+}
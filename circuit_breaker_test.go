@@ -0,0 +1,343 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	down := true
+	sandbox := kickbox.NewSandbox(kickbox.PrependSandboxRules(kickbox.SandboxRule{
+		Match: func(string) bool { return down },
+		Err:   &kickbox.APIError{HTTPStatus: http.StatusServiceUnavailable},
+	}))
+	verifier := kickbox.CircuitBreakerMiddleware(2, 20*time.Millisecond)(sandbox)
+
+	for i := 0; i < 2; i++ {
+		_, _, err := verifier.Verify(context.TODO(), "deliverable@example.com")
+		assert.True(t, errors.Is(err, kickbox.ErrServerError))
+	}
+
+	// open, fails fast
+	_, _, err := verifier.Verify(context.TODO(), "deliverable@example.com")
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+	_, err = verifier.VerifyBatchCheck(context.TODO(), "1")
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+
+	// half-open, the failed trial opens it again
+	time.Sleep(25 * time.Millisecond)
+	_, _, err = verifier.Verify(context.TODO(), "deliverable@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrServerError))
+	_, _, err = verifier.Verify(context.TODO(), "deliverable@example.com")
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+
+	// half-open, the successful trial closes it
+	down = false
+	time.Sleep(25 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		_, _, err = verifier.Verify(context.TODO(), "deliverable@example.com")
+		assert.Nil(t, err)
+	}
+}
+
+func TestCircuitBreakerIgnoresPermanentErrors(t *testing.T) {
+	sandbox := kickbox.NewSandbox(kickbox.PrependSandboxRules(kickbox.SandboxRule{
+		Match: func(string) bool { return true },
+		Err:   &kickbox.APIError{HTTPStatus: http.StatusUnauthorized},
+	}))
+	verifier := kickbox.CircuitBreakerMiddleware(1, time.Minute)(sandbox)
+
+	for i := 0; i < 3; i++ {
+		_, _, err := verifier.Verify(context.TODO(), "deliverable@example.com")
+		assert.True(t, errors.Is(err, kickbox.ErrUnauthorized))
+	}
+}
+
+func TestCircuitBreakerMiddlewareInvalidSettings(t *testing.T) {
+	assert.PanicsWithValue(t, "kickbox: CircuitBreakerMiddleware threshold must be greater than zero", func() {
+		kickbox.CircuitBreakerMiddleware(0, time.Second)
+	})
+	assert.PanicsWithValue(t, "kickbox: CircuitBreakerMiddleware: durations cannot be negative", func() {
+		kickbox.CircuitBreakerMiddleware(1, -time.Second)
+	})
+	assert.NotPanics(t, func() {
+		kickbox.CircuitBreakerMiddleware(1, 0)
+	})
+}
+
+func TestCircuitState(t *testing.T) {
+	assert.Equal(t, "closed", kickbox.CircuitClosed.String())
+	assert.Equal(t, "open", kickbox.CircuitOpen.String())
+	assert.Equal(t, "half-open", kickbox.CircuitHalfOpen.String())
+}
+
+func TestNewCircuitBreakerErrors(t *testing.T) {
+	tests := map[string]kickbox.CircuitBreakerSettings{
+		"consecutive failures or failure rate must be set": {},
+		"consecutive failures cannot be negative":          {ConsecutiveFailures: -1},
+		"failure rate must be between 0 and 1":             {FailureRate: 1.5},
+		"request counts cannot be negative":                {FailureRate: 0.5, MinRequests: -1},
+		"durations cannot be negative":                     {FailureRate: 0.5, Cooldown: -time.Second},
+	}
+	for want, settings := range tests {
+		_, err := kickbox.NewCircuitBreaker(settings)
+		assert.EqualError(t, err, want)
+	}
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	breaker, err := kickbox.NewCircuitBreaker(kickbox.CircuitBreakerSettings{
+		FailureRate: 0.5,
+		MinRequests: 4,
+		Window:      time.Minute,
+	})
+	assert.Nil(t, err)
+
+	serverErr := &kickbox.APIError{HTTPStatus: http.StatusBadGateway}
+	for _, err := range []error{nil, serverErr, nil} {
+		generation, allowErr := breaker.Allow()
+		assert.Nil(t, allowErr)
+		breaker.Record(generation, err)
+	}
+	assert.Equal(t, kickbox.CircuitClosed, breaker.State())
+
+	// 2 failures out of 4 requests
+	generation, err := breaker.Allow()
+	assert.Nil(t, err)
+	breaker.Record(generation, serverErr)
+	assert.Equal(t, kickbox.CircuitOpen, breaker.State())
+	_, err = breaker.Allow()
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+}
+
+func TestCircuitBreakerHalfOpenTrials(t *testing.T) {
+	var mu sync.Mutex
+	var transitions []string
+	breaker, err := kickbox.NewCircuitBreaker(kickbox.CircuitBreakerSettings{
+		ConsecutiveFailures: 1,
+		Cooldown:            10 * time.Millisecond,
+		HalfOpenRequests:    2,
+		OnStateChange: func(from, to kickbox.CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, from.String()+">"+to.String())
+		},
+	})
+	assert.Nil(t, err)
+
+	generation, err := breaker.Allow()
+	assert.Nil(t, err)
+	breaker.Record(generation, context.DeadlineExceeded)
+	assert.Equal(t, kickbox.CircuitOpen, breaker.State())
+
+	time.Sleep(15 * time.Millisecond)
+	assert.Equal(t, kickbox.CircuitHalfOpen, breaker.State())
+
+	// two trials allowed, the third one fails fast
+	trial, err := breaker.Allow()
+	assert.Nil(t, err)
+	_, err = breaker.Allow()
+	assert.Nil(t, err)
+	_, err = breaker.Allow()
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+
+	// a canceled trial frees its slot
+	breaker.Record(trial, context.Canceled)
+	_, err = breaker.Allow()
+	assert.Nil(t, err)
+
+	breaker.Record(trial, nil)
+	assert.Equal(t, kickbox.CircuitHalfOpen, breaker.State())
+	breaker.Record(trial, nil)
+	assert.Equal(t, kickbox.CircuitClosed, breaker.State())
+
+	assert.Equal(t, []string{"closed>open", "open>half-open", "half-open>closed"}, transitions)
+}
+
+func TestCircuitBreakerIgnoresEarlierGenerations(t *testing.T) {
+	breaker, err := kickbox.NewCircuitBreaker(kickbox.CircuitBreakerSettings{
+		ConsecutiveFailures: 1,
+		Cooldown:            10 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+
+	// a slow request allowed while closed
+	slow, err := breaker.Allow()
+	assert.Nil(t, err)
+
+	generation, err := breaker.Allow()
+	assert.Nil(t, err)
+	breaker.Record(generation, context.DeadlineExceeded)
+	assert.Equal(t, kickbox.CircuitOpen, breaker.State())
+
+	time.Sleep(15 * time.Millisecond)
+	trial, err := breaker.Allow()
+	assert.Nil(t, err)
+
+	// the slow request finishing while half-open is not the trial
+	breaker.Record(slow, nil)
+	assert.Equal(t, kickbox.CircuitHalfOpen, breaker.State())
+	breaker.Record(slow, context.DeadlineExceeded)
+	assert.Equal(t, kickbox.CircuitHalfOpen, breaker.State())
+	_, err = breaker.Allow()
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+
+	breaker.Record(trial, nil)
+	assert.Equal(t, kickbox.CircuitClosed, breaker.State())
+
+	// nor does the trial count once closed
+	breaker.Record(trial, context.DeadlineExceeded)
+	assert.Equal(t, kickbox.CircuitClosed, breaker.State())
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	svr, calls := flakyServer(100, http.StatusServiceUnavailable, nil)
+	defer svr.Close()
+
+	var opened int32
+	breaker, err := kickbox.NewCircuitBreaker(kickbox.CircuitBreakerSettings{
+		ConsecutiveFailures: 3,
+		Cooldown:            time.Minute,
+		OnStateChange: func(_, to kickbox.CircuitState) {
+			if to == kickbox.CircuitOpen {
+				atomic.AddInt32(&opened, 1)
+			}
+		},
+	})
+	assert.Nil(t, err)
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithCircuitBreaker(breaker),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 5, BaseBackoff: time.Millisecond}),
+	)
+	assert.Nil(t, err)
+
+	// the retries stop as soon as the breaker opens
+	_, _, err = client.Verify(context.TODO(), "user@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrCircuitOpen))
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&opened))
+
+	_, err = client.VerifyBatchCheck(context.TODO(), "123")
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+	_, err = kickbox.New("apikey", kickbox.WithCircuitBreaker(nil))
+	assert.EqualError(t, err, "applying optional settings: circuit breaker is nil")
+}
+
+// closeRecorder is a non seekable request body recording whether it was closed
+type closeRecorder struct {
+	io.Reader
+	closed int32
+}
+
+func (c *closeRecorder) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func TestClientCircuitOpenFailsFast(t *testing.T) {
+	var calls int32
+	svr := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer svr.Close()
+
+	breaker, err := kickbox.NewCircuitBreaker(kickbox.CircuitBreakerSettings{ConsecutiveFailures: 1, Cooldown: time.Minute})
+	assert.Nil(t, err)
+	generation, err := breaker.Allow()
+	assert.Nil(t, err)
+	breaker.Record(generation, context.DeadlineExceeded)
+
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithCircuitBreaker(breaker),
+		kickbox.CustomRateLimiter(limiter),
+		kickbox.MaxConcurrentConnections(1),
+		kickbox.FailFastConnections(),
+	)
+	assert.Nil(t, err)
+
+	// neither a rate limit token nor a connection are taken
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		_, _, err = client.Verify(ctx, "user@example.com")
+		assert.Equal(t, kickbox.ErrCircuitOpen, err)
+	}
+	assert.True(t, limiter.Allow(), "the token is left")
+
+	// the upload is closed, e.g. stopping the writer of a BatchInput
+	file := &closeRecorder{Reader: strings.NewReader("user@example.com\n")}
+	_, err = client.VerifyBatch(context.TODO(), file)
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&file.closed))
+
+	input := kickbox.BatchInputFromChan(make(chan string))
+	_, err = client.VerifyBatchInput(context.TODO(), input)
+	assert.Equal(t, kickbox.ErrCircuitOpen, err)
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestClientCircuitOpensWhileWaiting(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer svr.Close()
+
+	breaker, err := kickbox.NewCircuitBreaker(kickbox.CircuitBreakerSettings{ConsecutiveFailures: 1, Cooldown: time.Minute})
+	assert.Nil(t, err)
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithCircuitBreaker(breaker),
+		kickbox.MaxConcurrentConnections(1),
+	)
+	assert.Nil(t, err)
+
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := client.Verify(context.TODO(), "first@example.com")
+		first <- err
+	}()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// admitted while closed, then waiting for the connection
+	second := make(chan error, 1)
+	go func() {
+		_, _, err := client.Verify(context.TODO(), "second@example.com")
+		second <- err
+	}()
+	for client.ConnectionStats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the failure of the first one opens the breaker, the second one is not sent
+	close(release)
+	assert.True(t, errors.Is(<-first, kickbox.ErrServerError))
+	assert.Equal(t, kickbox.ErrCircuitOpen, <-second)
+	assert.Equal(t, kickbox.CircuitOpen, breaker.State())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -11,11 +11,20 @@ import (
 
 // ClientHTTP kickbox
 type ClientHTTP struct {
-	httpClient *http.Client
-	apiKey     string
-	baseURL    string
-	connPool   chan struct{}
-	rateLimit  *rate.Limiter
+	connWaiting     int32 // accessed atomically
+	httpClient      *http.Client
+	downloadClient  *http.Client
+	apiKey          string
+	headerAuth      bool
+	validateSyntax  bool
+	breaker         *CircuitBreaker
+	adaptive        *AdaptiveRateLimiter
+	baseURL         string
+	connPool        chan struct{}
+	connFailFast    bool
+	connWaitTimeout time.Duration
+	rateLimit       *rate.Limiter
+	retry           RetryPolicy
 }
 
 // Ensure Verifier implementation
@@ -27,6 +36,13 @@ type ClientHTTPOptions struct {
 	maxConcurrentConnections uint
 	httpClient               *http.Client
 	rateLimiter              *rate.Limiter
+	retryPolicy              RetryPolicy
+	failFastConnections      bool
+	connectionWaitTimeout    time.Duration
+	headerAuthentication     bool
+	validateSyntax           bool
+	circuitBreaker           *CircuitBreaker
+	adaptiveRateLimiter      *AdaptiveRateLimiter
 }
 
 // ClientHTTPOption signature
@@ -55,7 +71,8 @@ func MaxConcurrentConnections(num uint) ClientHTTPOption {
 	}
 }
 
-// CustomRateLimiter sets a custom rate limiter
+// CustomRateLimiter sets a custom rate limiter. Default: 8000 per minute, with a burst
+// of the max concurrent connections so they can all be used at once
 // see: https://docs.kickbox.com/docs/using-the-api#api-limits
 func CustomRateLimiter(r *rate.Limiter) ClientHTTPOption {
 	return func(o *ClientHTTPOptions) error {
@@ -78,6 +95,16 @@ func CustomHTTPClient(client *http.Client) ClientHTTPOption {
 	}
 }
 
+// ValidateSyntaxLocally checks the email syntax before calling the API. Invalid
+// emails are answered locally as undeliverable invalid_email, saving the credit.
+// see: ValidateSyntax
+func ValidateSyntaxLocally() ClientHTTPOption {
+	return func(o *ClientHTTPOptions) error {
+		o.validateSyntax = true
+		return nil
+	}
+}
+
 // New creates a new kickbox HTTP API client
 func New(apiKey string, opts ...ClientHTTPOption) (*ClientHTTP, error) {
 	if apiKey == "" {
@@ -90,7 +117,7 @@ func New(apiKey string, opts ...ClientHTTPOption) (*ClientHTTP, error) {
 		baseURL:                  BaseURL,
 		maxConcurrentConnections: maxConcurrentConnections,
 		httpClient:               &http.Client{Timeout: defaultClientTimeout},
-		rateLimiter:              rate.NewLimiter(rate.Limit(maxRatePerMinute), 1),
+		retryPolicy:              defaultRetryPolicy,
 	}
 
 	for _, o := range opts {
@@ -99,11 +126,96 @@ func New(apiKey string, opts ...ClientHTTPOption) (*ClientHTTP, error) {
 		}
 	}
 
+	// the adaptive limiter adjusts the rate limiter, whatever the order of the options
+	if a := options.adaptiveRateLimiter; a != nil {
+		if options.rateLimiter != nil && options.rateLimiter != a.limiter {
+			return nil, errors.New("applying optional settings: the custom rate limiter is not adjusted by the adaptive rate limiter, see AdaptiveRateLimiterSettings.Limiter")
+		}
+		options.rateLimiter = a.limiter
+	}
+	if options.rateLimiter == nil {
+		options.rateLimiter = rate.NewLimiter(rate.Limit(maxRatePerMinute), int(options.maxConcurrentConnections))
+	}
+
+	// the results are streamed for as long as the context allows, the client
+	// timeout would abort the download of large batches
+	downloadClient := *options.httpClient
+	downloadClient.Timeout = 0
+
 	return &ClientHTTP{
-		apiKey:     apiKey,
-		httpClient: options.httpClient,
-		baseURL:    options.baseURL,
-		connPool:   make(chan struct{}, options.maxConcurrentConnections),
-		rateLimit:  options.rateLimiter,
+		apiKey:          apiKey,
+		headerAuth:      options.headerAuthentication,
+		validateSyntax:  options.validateSyntax,
+		breaker:         options.circuitBreaker,
+		adaptive:        options.adaptiveRateLimiter,
+		httpClient:      options.httpClient,
+		downloadClient:  &downloadClient,
+		baseURL:         options.baseURL,
+		connPool:        make(chan struct{}, options.maxConcurrentConnections),
+		connFailFast:    options.failFastConnections,
+		connWaitTimeout: options.connectionWaitTimeout,
+		rateLimit:       options.rateLimiter,
+		retry:           options.retryPolicy,
 	}, nil
 }
+
+// failFast returns ErrCircuitOpen while the circuit breaker is open, before waiting for
+// the rate limiter or a free connection. No trial request is taken, do checks the
+// breaker again right before sending.
+func (c *ClientHTTP) failFast() error {
+	if c.breaker != nil && c.breaker.rejecting() {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// allow checks the circuit breaker right before sending a request, failing with ErrCircuitOpen
+// while it is open. The generation must be passed to send.
+func (c *ClientHTTP) allow() (uint64, error) {
+	if c.breaker == nil {
+		return 0, nil
+	}
+	return c.breaker.Allow()
+}
+
+// do sends the request once allowed by the circuit breaker. While it is open ErrCircuitOpen
+// is returned and the body of the request is closed, as the http client would have.
+func (c *ClientHTTP) do(req *http.Request) (*http.Response, error) {
+	generation, err := c.allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return c.send(req, generation)
+}
+
+// send sends the request allowed in the circuit breaker generation, recording its outcome.
+// The api key is redacted from the returned error.
+func (c *ClientHTTP) send(req *http.Request, generation uint64) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+
+	if c.breaker != nil {
+		c.breaker.Record(generation, responseError(resp, err))
+	}
+	if c.adaptive != nil {
+		c.adaptive.observe(resp, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("doing request: %w", c.redact(err))
+	}
+	return resp, nil
+}
+
+// responseError is the error of the request outcome, as classified by the circuit breaker.
+// The body of the response is not read, an *APIError is returned for the non 2xx status codes.
+func responseError(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{HTTPStatus: resp.StatusCode, Header: resp.Header}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package kickbox
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces the api key in errors and string outputs
+const redacted = "REDACTED"
+
+// HeaderAuthentication sends the api key in the Authorization header instead of
+// the apikey query param, keeping it out of urls, proxies and access logs
+func HeaderAuthentication() ClientHTTPOption {
+	return func(o *ClientHTTPOptions) error {
+		o.headerAuthentication = true
+		return nil
+	}
+}
+
+// String describes the client without exposing the api key
+func (c *ClientHTTP) String() string {
+	auth := "query"
+	if c.headerAuth {
+		auth = "header"
+	}
+	return fmt.Sprintf("kickbox.ClientHTTP{baseURL: %q, apiKey: %s, auth: %s}", c.baseURL, redacted, auth)
+}
+
+// GoString describes the client for %#v without exposing the api key
+func (c *ClientHTTP) GoString() string {
+	return c.String()
+}
+
+// authenticate adds the api key to the request
+func (c *ClientHTTP) authenticate(req *http.Request) {
+	if c.headerAuth {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		return
+	}
+
+	q := req.URL.Query()
+	q.Set("apikey", c.apiKey)
+	req.URL.RawQuery = q.Encode()
+}
+
+// redact removes the api key from err. A *url.Error is replaced by a copy
+// with the redacted url so errors.As never exposes the original one.
+func (c *ClientHTTP) redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		copied := *urlErr
+		copied.URL = redactURL(urlErr.URL, c.apiKey)
+		copied.Err = c.redactMessage(urlErr.Err)
+		return &copied
+	}
+	return c.redactMessage(err)
+}
+
+// redactMessage hides the api key from the message of err, if present
+func (c *ClientHTTP) redactMessage(err error) error {
+	if err == nil || !strings.Contains(err.Error(), c.apiKey) {
+		return err
+	}
+	return &redactedError{
+		msg: strings.ReplaceAll(err.Error(), c.apiKey, redacted),
+		err: err,
+	}
+}
+
+// redactURL replaces the apikey query param and any other occurrence of key in the
+// query values or path of rawURL
+func redactURL(rawURL, key string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for name, values := range q {
+			for i, v := range values {
+				if name == "apikey" {
+					values[i] = redacted
+				} else {
+					values[i] = strings.ReplaceAll(v, key, redacted)
+				}
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	if strings.Contains(u.Path, key) {
+		u.Path = strings.ReplaceAll(u.Path, key, redacted)
+		u.RawPath = ""
+	}
+	return u.String()
+}
+
+// redactedError is an error whose message had the api key removed
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap keeps the original error reachable by errors.Is
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
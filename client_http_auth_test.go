@@ -0,0 +1,71 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderAuthentication(t *testing.T) {
+	var authorization, query string
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		query = r.URL.RawQuery
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(`{"result":"deliverable","success":true}`))
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("s3cr3t-key",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.HeaderAuthentication(),
+	)
+	assert.Nil(t, err)
+
+	_, _, err = client.Verify(context.TODO(), "email@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer s3cr3t-key", authorization)
+	assert.Equal(t, "email=email%40example.com&timeout=6000", query)
+
+	_, err = client.VerifyBatchCheck(context.TODO(), "123")
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer s3cr3t-key", authorization)
+	assert.Empty(t, query)
+}
+
+func TestRedactedURLError(t *testing.T) {
+	svr := httptest.NewServer(http.NotFoundHandler())
+	svr.Close() // nothing listening
+
+	client, err := kickbox.New("s3cr3t-key", kickbox.OverrideBaseURL(svr.URL))
+	assert.Nil(t, err)
+
+	_, err = client.VerifyBatchCheck(context.TODO(), "123")
+	assert.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t-key")
+
+	var urlErr *url.Error
+	assert.True(t, errors.As(err, &urlErr))
+	assert.Equal(t, svr.URL+"/v2/verify-batch/123?apikey=REDACTED", urlErr.URL)
+}
+
+func TestClientString(t *testing.T) {
+	client, err := kickbox.New("s3cr3t-key", kickbox.HeaderAuthentication())
+	assert.Nil(t, err)
+
+	for _, format := range []string{"%s", "%v", "%+v", "%#v"} {
+		out := fmt.Sprintf(format, client)
+		assert.NotContains(t, out, "s3cr3t-key", format)
+		assert.Contains(t, out, "REDACTED", format)
+	}
+}
@@ -50,11 +50,11 @@ func RequestTimeout(d time.Duration) VerifyBatchOption {
 	}
 }
 
-// VerifyBatch Verify batches of up to 1 million email addresses asynchronously from a single request
+// VerifyBatch Verify batches of up to 1 million email addresses asynchronously from a single request,
+// see SubmitLargeBatch for bigger files
+// The upload is only retried when the file implements io.Seeker, e.g. *os.File
 // see: https://docs.kickbox.com/docs/batch-verification-api
 func (c *ClientHTTP) VerifyBatch(ctx context.Context, file io.ReadCloser, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
-	const verifyBatchPath = "/v2/verify-batch"
-
 	// Default options
 	const defaultRequestTimeout = 30 * time.Second
 	options := VerifyBatchRequestOptions{
@@ -64,19 +64,50 @@ func (c *ClientHTTP) VerifyBatch(ctx context.Context, file io.ReadCloser, opts .
 		apply(&options)
 	}
 
+	// A seekable file can be rewinded and sent again, the http client must not close it
+	body := io.Reader(file)
+	seeker, replayable := file.(io.Seeker)
+	var offset int64
+	if replayable {
+		var err error
+		if offset, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			replayable = false
+		} else {
+			body = io.NopCloser(file)
+			defer file.Close()
+		}
+	}
+
+	var response *ResponseVerifyBatch
+	err := retry(ctx, c.retry, replayable, func(n int) error {
+		if n > 1 {
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return fmt.Errorf("rewinding file: %v", err)
+			}
+		}
+
+		var err error
+		response, err = c.verifyBatch(ctx, body, options)
+		return err
+	})
+
+	return response, err
+}
+
+// verifyBatch makes a single upload attempt to the batch verification endpoint
+func (c *ClientHTTP) verifyBatch(ctx context.Context, body io.Reader, options VerifyBatchRequestOptions) (*ResponseVerifyBatch, error) {
+	const verifyBatchPath = "/v2/verify-batch"
+
 	ctx, cancel := context.WithTimeout(ctx, options.timeout)
 	defer cancel()
 
 	requestURL := c.baseURL + verifyBatchPath
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, requestURL, file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, requestURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %v", err)
 	}
 
-	// Adds query params
-	q := req.URL.Query()
-	q.Add("apikey", c.apiKey)
-	req.URL.RawQuery = q.Encode()
+	c.authenticate(req)
 
 	// Adds optional headers
 	if options.filename != "" {
@@ -88,12 +119,16 @@ func (c *ClientHTTP) VerifyBatch(ctx context.Context, file io.ReadCloser, opts .
 
 	req.Header.Add("Content-Type", "text/csv")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("doing request: %v", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
 	var response ResponseVerifyBatch
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, fmt.Errorf("decoding response: %v", err)
@@ -12,31 +12,17 @@ import (
 // VeryfyBatchCheckResponse
 // see: https://docs.kickbox.com/docs/batch-verification-api#example-responses
 type VerifyBatchCheckResponse struct {
-	ID      int    `json:"id"`      // 123,
-	Status  string `json:"status"`  // "starting", "processing", "completed", "failed",
-	Success bool   `json:"success"` // true,
-	Message string `json:"message"` // null
+	ID      int         `json:"id"`      // 123,
+	Status  BatchStatus `json:"status"`  // "starting", "processing", "completed", "failed",
+	Success bool        `json:"success"` // true,
+	Message string      `json:"message"` // null
 
 	// when Status is "processing"
-	Progress struct {
-		Deliverable   int `json:"deliverable"`   // 1,
-		Undeliverable int `json:"undeliverable"` // 0,
-		Risky         int `json:"risky"`         // 0,
-		Unknown       int `json:"unknown"`       // 0,
-		Total         int `json:"total"`         // 3,
-		Unprocessed   int `json:"unprocessed"`   // 2
-	} `json:"progress"`
+	Progress BatchProgress `json:"progress"`
 
 	// when Status is completed
-	DownloadURL string `json:"download_url"` // "https://{{DOWNLOAD_URL_HERE}}",
-	Stats       struct {
-		Deliverable   int     `json:"deliverable"`   // 2,
-		Undeliverable int     `json:"undeliverable"` // 1,
-		Risky         int     `json:"risky"`         // 0,
-		Unknown       int     `json:"unknown"`       // 0,
-		Sendex        float64 `json:"sendex"`        // 0.35,
-		Addresses     int     `json:"addresses"`     // 3
-	} `json:"stats"`
+	DownloadURL string     `json:"download_url"` // "https://{{DOWNLOAD_URL_HERE}}",
+	Stats       BatchStats `json:"stats"`
 
 	// when Status is completed OR failed
 	Name      string `json:"name"`       // "Batch API Process - 05-12-2018-01-58-08",
@@ -45,15 +31,47 @@ type VerifyBatchCheckResponse struct {
 	Duration  int    `json:"duration"`   // 0,
 }
 
+// BatchProgress is the progress of a batch job while its status is "processing"
+type BatchProgress struct {
+	Deliverable   int `json:"deliverable"`   // 1,
+	Undeliverable int `json:"undeliverable"` // 0,
+	Risky         int `json:"risky"`         // 0,
+	Unknown       int `json:"unknown"`       // 0,
+	Total         int `json:"total"`         // 3,
+	Unprocessed   int `json:"unprocessed"`   // 2
+}
+
+// BatchStats are the final figures of a batch job once its status is "completed"
+type BatchStats struct {
+	Deliverable   int     `json:"deliverable"`   // 2,
+	Undeliverable int     `json:"undeliverable"` // 1,
+	Risky         int     `json:"risky"`         // 0,
+	Unknown       int     `json:"unknown"`       // 0,
+	Sendex        float64 `json:"sendex"`        // 0.35,
+	Addresses     int     `json:"addresses"`     // 3
+}
+
 // VerifyBatchCheck Checking a Batch Verification Status
 // see: https://docs.kickbox.com/docs/batch-verification-api#checking-a-batch-verification-status
 func (c *ClientHTTP) VerifyBatchCheck(ctx context.Context, batchID string) (*VerifyBatchCheckResponse, error) {
-	const verifyPath = "/v2/verify-batch/"
-
 	if batchID == "" {
 		return nil, errors.New("batch id is empty")
 	}
 
+	var response *VerifyBatchCheckResponse
+	err := retry(ctx, c.retry, true, func(_ int) error {
+		var err error
+		response, err = c.verifyBatchCheck(ctx, batchID)
+		return err
+	})
+
+	return response, err
+}
+
+// verifyBatchCheck makes a single request attempt to the batch status endpoint
+func (c *ClientHTTP) verifyBatchCheck(ctx context.Context, batchID string) (*VerifyBatchCheckResponse, error) {
+	const verifyPath = "/v2/verify-batch/"
+
 	const timeoutDuration = 30 * time.Second
 	ctx, cancel := context.WithTimeout(ctx, timeoutDuration)
 	defer cancel()
@@ -65,17 +83,18 @@ func (c *ClientHTTP) VerifyBatchCheck(ctx context.Context, batchID string) (*Ver
 		return nil, fmt.Errorf("building request: %v", err)
 	}
 
-	// Adds query params
-	q := req.URL.Query()
-	q.Add("apikey", c.apiKey)
-	req.URL.RawQuery = q.Encode()
+	c.authenticate(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("doing request: %v", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
 	// Parse the the body response
 	var body VerifyBatchCheckResponse
 	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
@@ -0,0 +1,65 @@
+package kickbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// DownloadBatchResults downloads the results file of a completed batch job.
+// Results are streamed, the returned iterator must be closed.
+// see: https://docs.kickbox.com/docs/batch-verification-api#checking-a-batch-verification-status
+func (c *ClientHTTP) DownloadBatchResults(ctx context.Context, check *VerifyBatchCheckResponse) (*BatchResults, error) {
+	if err := downloadable(check); err != nil {
+		return nil, err
+	}
+
+	var resp *http.Response
+	err := retry(ctx, c.retry, true, func(_ int) error {
+		var err error
+		resp, err = c.downloadBatchResults(ctx, check.DownloadURL)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseBatchResults(resp.Body)
+}
+
+// downloadBatchResults makes a single request attempt to the download url. The file is
+// served by the storage, not the API: the circuit breaker and the adaptive rate limiter are
+// bypassed and the client timeout does not apply, the download is bounded by the context.
+func (c *ClientHTTP) downloadBatchResults(ctx context.Context, downloadURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %v", err)
+	}
+
+	resp, err := c.downloadClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("doing request: %w", c.redact(err))
+	}
+
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// downloadable checks the batch job has results to download
+func downloadable(check *VerifyBatchCheckResponse) error {
+	if check == nil {
+		return errors.New("batch check response is nil")
+	}
+	if check.Status != BatchCompleted {
+		return fmt.Errorf("batch %d is not completed: %s", check.ID, check.Status)
+	}
+	if check.DownloadURL == "" {
+		return fmt.Errorf("batch %d has no download url", check.ID)
+	}
+	return nil
+}
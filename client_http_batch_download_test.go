@@ -0,0 +1,191 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const batchResultsCSV = `Email,Result,Reason,Role,Free,Disposable,Accept All,Did You Mean,Sendex,User,Domain
+bill.lumbergh@gamil.com,undeliverable,rejected_email,false,false,false,false,bill.lumbergh@gmail.com,0.23,bill.lumbergh,gamil.com
+support@example.com,risky,low_quality,true,false,false,true,,0.6,support,example.com
+"peter,gibbons@example.com",deliverable,accepted_email,false,true,false,false,,1,"peter,gibbons",example.com
+`
+
+func TestDownloadBatchResults(t *testing.T) {
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/results/123.csv", r.URL.Path)
+		rw.Header().Set("Content-Type", "text/csv")
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(batchResultsCSV))
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey", kickbox.CustomHTTPClient(svr.Client()))
+	assert.Nil(t, err)
+
+	results, err := client.DownloadBatchResults(context.TODO(), &kickbox.VerifyBatchCheckResponse{
+		ID:          123,
+		Status:      "completed",
+		DownloadURL: svr.URL + "/results/123.csv",
+	})
+	assert.Nil(t, err)
+	defer results.Close()
+
+	var got []*kickbox.BatchResult
+	for results.Next() {
+		got = append(got, results.Result())
+	}
+	assert.Nil(t, results.Err())
+	assert.Len(t, got, 3)
+
+	assert.Equal(t, 1, got[0].Row)
+	assert.Equal(t, kickbox.ResponseVerify{
+		Result:     "undeliverable",
+		Reason:     "rejected_email",
+		DidYouMean: "bill.lumbergh@gmail.com",
+		Sendex:     0.23,
+		Email:      "bill.lumbergh@gamil.com",
+		User:       "bill.lumbergh",
+		Domain:     "gamil.com",
+		Success:    true,
+	}, got[0].ResponseVerify)
+	assert.Equal(t, "false", got[0].Columns["Accept All"])
+
+	assert.True(t, got[1].Role)
+	assert.True(t, got[1].AcceptAll)
+	assert.Equal(t, 0.6, got[1].Sendex)
+
+	assert.Equal(t, "peter,gibbons@example.com", got[2].Email)
+	assert.True(t, got[2].Free)
+}
+
+func TestDownloadBatchResultsErrors(t *testing.T) {
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		_, _ = rw.Write([]byte(`{"success":false,"message":"Not found"}`))
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey")
+	assert.Nil(t, err)
+
+	_, err = client.DownloadBatchResults(context.TODO(), nil)
+	assert.EqualError(t, err, "batch check response is nil")
+
+	_, err = client.DownloadBatchResults(context.TODO(), &kickbox.VerifyBatchCheckResponse{ID: 1, Status: "processing"})
+	assert.EqualError(t, err, "batch 1 is not completed: processing")
+
+	_, err = client.DownloadBatchResults(context.TODO(), &kickbox.VerifyBatchCheckResponse{ID: 1, Status: "completed"})
+	assert.EqualError(t, err, "batch 1 has no download url")
+
+	_, err = client.DownloadBatchResults(context.TODO(), &kickbox.VerifyBatchCheckResponse{
+		ID:          1,
+		Status:      "completed",
+		DownloadURL: svr.URL,
+	})
+	var apiErr *kickbox.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.HTTPStatus)
+}
+
+func TestDownloadBatchResultsSlowStream(t *testing.T) {
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unavailable.csv" {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		lines := strings.SplitAfter(batchResultsCSV, "\n")
+		for _, line := range lines {
+			_, _ = rw.Write([]byte(line))
+			rw.(http.Flusher).Flush()
+			time.Sleep(40 * time.Millisecond)
+		}
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	breaker, err := kickbox.NewCircuitBreaker(kickbox.CircuitBreakerSettings{ConsecutiveFailures: 1})
+	assert.Nil(t, err)
+
+	// the client timeout is shorter than the download
+	httpClient := svr.Client()
+	httpClient.Timeout = 50 * time.Millisecond
+	client, err := kickbox.New("apikey", kickbox.CustomHTTPClient(httpClient), kickbox.WithCircuitBreaker(breaker))
+	assert.Nil(t, err)
+
+	results, err := client.DownloadBatchResults(context.TODO(), &kickbox.VerifyBatchCheckResponse{
+		ID:          123,
+		Status:      "completed",
+		DownloadURL: svr.URL + "/results/123.csv",
+	})
+	assert.Nil(t, err)
+	defer results.Close()
+
+	rows := 0
+	for results.Next() {
+		rows++
+	}
+	assert.Nil(t, results.Err())
+	assert.Equal(t, 3, rows)
+
+	// storage errors do not trip the API circuit breaker
+	_, err = client.DownloadBatchResults(context.TODO(), &kickbox.VerifyBatchCheckResponse{
+		ID:          124,
+		Status:      "completed",
+		DownloadURL: svr.URL + "/unavailable.csv",
+	})
+	assert.True(t, errors.Is(err, kickbox.ErrServerError))
+	assert.Equal(t, kickbox.CircuitClosed, breaker.State())
+}
+
+func TestParseBatchResultsStreaming(t *testing.T) {
+	// rows are generated on demand, the file is never held in memory
+	const rows = 100000
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = fmt.Fprintln(pw, "email,result,sendex")
+		for i := 0; i < rows; i++ {
+			_, _ = fmt.Fprintf(pw, "user%d@example.com,deliverable,1\n", i)
+		}
+		pw.Close()
+	}()
+
+	results, err := kickbox.ParseBatchResults(pr)
+	assert.Nil(t, err)
+	defer results.Close()
+
+	total := 0
+	for results.Next() {
+		total++
+		assert.Equal(t, fmt.Sprintf("user%d@example.com", total-1), results.Result().Email)
+	}
+	assert.Nil(t, results.Err())
+	assert.Equal(t, rows, total)
+}
+
+func TestParseBatchResultsInvalid(t *testing.T) {
+	_, err := kickbox.ParseBatchResults(io.NopCloser(strings.NewReader("")))
+	assert.EqualError(t, err, "reading results header: empty file")
+
+	results, err := kickbox.ParseBatchResults(io.NopCloser(strings.NewReader("email,role\na@example.com,maybe\nb@example.com,true\n")))
+	assert.Nil(t, err)
+	assert.False(t, results.Next())
+	assert.EqualError(t, results.Err(), `reading results row 1: column "role": invalid boolean: "maybe"`)
+	assert.False(t, results.Next())
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -27,7 +27,7 @@ func TestVerifyBatch(t *testing.T) {
 	expectedFileNameHeader := "myfilename.response"
 	expectedCallbackHeader := "http://call.me.maybe"
 
-	expectedFileContent, err := ioutil.ReadFile(csvFilePath)
+	expectedFileContent, err := os.ReadFile(csvFilePath)
 	assert.Nil(t, err)
 
 	handler := func(rw http.ResponseWriter, r *http.Request) {
@@ -51,7 +51,7 @@ func TestVerifyBatch(t *testing.T) {
 		}
 
 		// Check body content
-		content, errRead := ioutil.ReadAll(r.Body)
+		content, errRead := io.ReadAll(r.Body)
 		if errRead != nil {
 			t.Logf("cannot read body content: %v", err)
 			rw.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,82 @@
+package kickbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors to classify an *APIError with errors.Is
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrServerError         = errors.New("server error")
+)
+
+// maxErrorBodySize limits the amount of bytes read from a non 2xx response body
+const maxErrorBodySize = 64 << 10
+
+// APIError is returned when the kickbox API responds with a non 2xx status code
+// see: https://docs.kickbox.com/docs/using-the-api#errors
+type APIError struct {
+	HTTPStatus int         // HTTP Status Response Code
+	Success    bool        // always false
+	Message    string      // kickbox error message, if any
+	Header     http.Header // response headers
+	Body       []byte      // raw response body
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("kickbox api error: status %d", e.HTTPStatus)
+	}
+	return fmt.Sprintf("kickbox api error: status %d: %s", e.HTTPStatus, e.Message)
+}
+
+// Is reports whether the error belongs to the failure class of target
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrInsufficientBalance:
+		return e.HTTPStatus == http.StatusPaymentRequired ||
+			strings.Contains(strings.ToLower(e.Message), "insufficient balance")
+	case ErrUnauthorized:
+		return (e.HTTPStatus == http.StatusUnauthorized || e.HTTPStatus == http.StatusForbidden) &&
+			!e.Is(ErrInsufficientBalance)
+	case ErrRateLimited:
+		return e.HTTPStatus == http.StatusTooManyRequests
+	case ErrServerError:
+		return e.HTTPStatus >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// checkResponse returns an *APIError when the response status code is not 2xx.
+// The response body is consumed but not closed.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+	apiErr := &APIError{
+		HTTPStatus: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}
+
+	// Best effort, the body may not be json at all
+	var payload struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		apiErr.Message = payload.Message
+	}
+
+	return apiErr
+}
@@ -0,0 +1,112 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIErrorClassification(t *testing.T) {
+	tests := map[string]struct {
+		status   int
+		body     string
+		sentinel error
+		message  string
+	}{
+		"bad request": {
+			status:  http.StatusBadRequest,
+			body:    `{"success":false,"message":"Missing email parameter"}`,
+			message: "Missing email parameter",
+		},
+		"unauthorized": {
+			status:   http.StatusUnauthorized,
+			body:     `{"success":false,"message":"Unauthorized"}`,
+			sentinel: kickbox.ErrUnauthorized,
+			message:  "Unauthorized",
+		},
+		"insufficient balance": {
+			status:   http.StatusForbidden,
+			body:     `{"success":false,"message":"Insufficient balance"}`,
+			sentinel: kickbox.ErrInsufficientBalance,
+			message:  "Insufficient balance",
+		},
+		"rate limited": {
+			status:   http.StatusTooManyRequests,
+			body:     `{"success":false,"message":"Too many requests"}`,
+			sentinel: kickbox.ErrRateLimited,
+			message:  "Too many requests",
+		},
+		"server error": {
+			status:   http.StatusInternalServerError,
+			body:     `<html>oops</html>`,
+			sentinel: kickbox.ErrServerError,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler := func(rw http.ResponseWriter, r *http.Request) {
+				rw.Header().Set("X-Kickbox-Balance", "0")
+				rw.WriteHeader(tc.status)
+				_, _ = rw.Write([]byte(tc.body))
+			}
+
+			svr := httptest.NewServer(http.HandlerFunc(handler))
+			defer svr.Close()
+
+			client, err := kickbox.New("apikey", kickbox.OverrideBaseURL(svr.URL))
+			assert.Nil(t, err)
+
+			calls := map[string]func() error{
+				"verify": func() error {
+					header, _, err := client.Verify(context.TODO(), "email@example.com")
+					assert.Equal(t, tc.status, header.HTTPStatus)
+					return err
+				},
+				"batch": func() error {
+					_, err := client.VerifyBatch(context.TODO(), http.NoBody)
+					return err
+				},
+				"batch check": func() error {
+					_, err := client.VerifyBatchCheck(context.TODO(), "123")
+					return err
+				},
+			}
+
+			for call, fn := range calls {
+				err := fn()
+
+				var apiErr *kickbox.APIError
+				assert.True(t, errors.As(err, &apiErr), call)
+				assert.Equal(t, tc.status, apiErr.HTTPStatus, call)
+				assert.False(t, apiErr.Success, call)
+				assert.Equal(t, tc.message, apiErr.Message, call)
+				assert.Equal(t, tc.body, string(apiErr.Body), call)
+				assert.Equal(t, "0", apiErr.Header.Get("X-Kickbox-Balance"), call)
+
+				for _, sentinel := range []error{
+					kickbox.ErrInsufficientBalance,
+					kickbox.ErrUnauthorized,
+					kickbox.ErrRateLimited,
+					kickbox.ErrServerError,
+				} {
+					assert.Equal(t, sentinel == tc.sentinel, errors.Is(err, sentinel), call+": "+sentinel.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	err := &kickbox.APIError{HTTPStatus: http.StatusBadRequest, Message: "Missing email parameter"}
+	assert.EqualError(t, err, "kickbox api error: status 400: Missing email parameter")
+
+	err = &kickbox.APIError{HTTPStatus: http.StatusBadGateway}
+	assert.EqualError(t, err, "kickbox api error: status 502")
+}
@@ -0,0 +1,87 @@
+package kickbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrMaxConnections is returned when no connection is available to do the request
+var ErrMaxConnections = errors.New("max connections opened")
+
+// ConnectionStats is a snapshot of the connection pool usage
+type ConnectionStats struct {
+	Max      int // maximum simultaneous connections
+	InFlight int // connections in use
+	Waiting  int // requests waiting for a free connection
+}
+
+// FailFastConnections makes Verify return ErrMaxConnections immediately when all
+// the connections are in use, instead of waiting for a free one
+func FailFastConnections() ClientHTTPOption {
+	return func(o *ClientHTTPOptions) error {
+		o.failFastConnections = true
+		return nil
+	}
+}
+
+// ConnectionWaitTimeout sets the maximum time Verify waits for a free connection.
+// By default it waits until the context is done.
+func ConnectionWaitTimeout(d time.Duration) ClientHTTPOption {
+	return func(o *ClientHTTPOptions) error {
+		if d <= 0 {
+			return errors.New("connection wait timeout must be greater than zero")
+		}
+		o.connectionWaitTimeout = d
+		return nil
+	}
+}
+
+// ConnectionStats returns the current usage of the connection pool
+func (c *ClientHTTP) ConnectionStats() ConnectionStats {
+	return ConnectionStats{
+		Max:      cap(c.connPool),
+		InFlight: len(c.connPool),
+		Waiting:  int(atomic.LoadInt32(&c.connWaiting)),
+	}
+}
+
+// acquireConn takes a connection from the pool, blocking until one is free
+// unless the client is configured to fail fast
+func (c *ClientHTTP) acquireConn(ctx context.Context) error {
+	select {
+	case c.connPool <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if c.connFailFast {
+		return fmt.Errorf("%w: %d", ErrMaxConnections, cap(c.connPool))
+	}
+
+	atomic.AddInt32(&c.connWaiting, 1)
+	defer atomic.AddInt32(&c.connWaiting, -1)
+
+	var timeout <-chan time.Time
+	if c.connWaitTimeout > 0 {
+		timer := time.NewTimer(c.connWaitTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case c.connPool <- struct{}{}:
+		return nil
+	case <-timeout:
+		return fmt.Errorf("waiting %v for a free connection: %w: %d", c.connWaitTimeout, ErrMaxConnections, cap(c.connPool))
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for a free connection: %w", ctx.Err())
+	}
+}
+
+// releaseConn returns a connection to the pool
+func (c *ClientHTTP) releaseConn() {
+	<-c.connPool
+}
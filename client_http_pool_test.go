@@ -0,0 +1,165 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+// slowServer holds every request until release is closed
+func slowServer(release <-chan struct{}) *httptest.Server {
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		<-release
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(`{}`))
+	}
+	return httptest.NewServer(http.HandlerFunc(handler))
+}
+
+func TestConnectionWaitTimeoutOption(t *testing.T) {
+	options := kickbox.ClientHTTPOptions{}
+	assert.EqualError(t, kickbox.ConnectionWaitTimeout(0)(&options), "connection wait timeout must be greater than zero")
+	assert.Nil(t, kickbox.ConnectionWaitTimeout(time.Second)(&options))
+	assert.Nil(t, kickbox.FailFastConnections()(&options))
+}
+
+func TestVerifyWaitsForFreeConnection(t *testing.T) {
+	release := make(chan struct{})
+	svr := slowServer(release)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.MaxConcurrentConnections(2),
+		kickbox.CustomRateLimiter(rate.NewLimiter(rate.Inf, 1)),
+	)
+	assert.Nil(t, err)
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := client.Verify(context.TODO(), "email@example.com")
+			errs <- err
+		}()
+	}
+
+	// 2 requests in flight, 1 waiting for a free connection
+	assert.Eventually(t, func() bool {
+		return client.ConnectionStats() == kickbox.ConnectionStats{Max: 2, InFlight: 2, Waiting: 1}
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, kickbox.ConnectionStats{Max: 2}, client.ConnectionStats())
+}
+
+func TestVerifyConnectionWaitTimeout(t *testing.T) {
+	release := make(chan struct{})
+	svr := slowServer(release)
+	defer svr.Close()
+	defer close(release)
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.MaxConcurrentConnections(1),
+		kickbox.ConnectionWaitTimeout(50*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	go func() {
+		_, _, _ = client.Verify(context.TODO(), "email@example.com")
+	}()
+	assert.Eventually(t, func() bool {
+		return client.ConnectionStats().InFlight == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, _, err = client.Verify(context.TODO(), "email@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrMaxConnections))
+	assert.EqualError(t, err, "waiting 50ms for a free connection: max connections opened: 1")
+}
+
+func TestVerifyConnectionWaitContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	svr := slowServer(release)
+	defer svr.Close()
+	defer close(release)
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.MaxConcurrentConnections(1),
+	)
+	assert.Nil(t, err)
+
+	go func() {
+		_, _, _ = client.Verify(context.TODO(), "email@example.com")
+	}()
+	assert.Eventually(t, func() bool {
+		return client.ConnectionStats().InFlight == 1
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err = client.Verify(ctx, "email@example.com")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.EqualError(t, err, "waiting for a free connection: context deadline exceeded")
+}
+
+func TestVerifyReleasesConnectionBetweenAttempts(t *testing.T) {
+	throttled := make(chan struct{})
+	var once sync.Once
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("email") == "throttled@example.com" {
+			first := false
+			once.Do(func() { first = true })
+			if first {
+				rw.Header().Set("Retry-After", "1")
+				rw.WriteHeader(http.StatusTooManyRequests)
+				close(throttled)
+				return
+			}
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(`{}`))
+	}
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.MaxConcurrentConnections(1),
+		kickbox.ConnectionWaitTimeout(500*time.Millisecond),
+		kickbox.CustomRateLimiter(rate.NewLimiter(rate.Inf, 1)),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 2}),
+	)
+	assert.Nil(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, _, err := client.Verify(context.TODO(), "throttled@example.com")
+		done <- err
+	}()
+	<-throttled
+
+	// the backoff of the throttled request does not hold the only connection
+	_, _, err = client.Verify(context.TODO(), "email@example.com")
+	assert.Nil(t, err)
+	assert.Nil(t, <-done)
+	assert.Equal(t, kickbox.ConnectionStats{Max: 1}, client.ConnectionStats())
+}
@@ -0,0 +1,228 @@
+package kickbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// adaptiveDecreaseGap is the minimum time between two rate decreases, so a burst
+// of throttled concurrent requests counts as a single signal
+const adaptiveDecreaseGap = time.Second
+
+// maxRetryAfterPause caps the pause honoring a Retry-After header
+const maxRetryAfterPause = 5 * time.Minute
+
+// AdaptiveRateLimiterSettings configures an AdaptiveRateLimiter
+type AdaptiveRateLimiterSettings struct {
+	// Min is the lowest rate, in requests per second. Default: 1
+	Min rate.Limit
+	// Max is the highest rate, in requests per second. Default: the kickbox limit, 8000 per minute
+	Max rate.Limit
+	// Initial is the starting rate. Default: Max
+	Initial rate.Limit
+	// Burst is the number of requests allowed at once. Default: the burst of the Limiter,
+	// or the default max concurrent connections, 25, without it
+	Burst int
+	// Backoff multiplies the rate when throttled, between 0 and 1. Default: 0.5
+	Backoff float64
+	// Step is added to the rate after every Interval without being throttled. Default: 5% of Max
+	Step rate.Limit
+	// Interval between two rate increases. Default: 10 seconds
+	Interval time.Duration
+	// Limiter is the limiter adjusted, e.g. the one also given to CustomRateLimiter.
+	// Default: a new limiter
+	Limiter *rate.Limiter
+}
+
+// AdaptiveRateLimiter adjusts the rate of a *rate.Limiter to the server feedback:
+// it backs off multiplicatively when throttled (429 or Retry-After) and ramps back
+// up additively while the requests succeed, within the min and max rates.
+// It is safe for concurrent use.
+type AdaptiveRateLimiter struct {
+	limiter  *rate.Limiter
+	settings AdaptiveRateLimiterSettings
+
+	mu           sync.Mutex
+	lastDecrease time.Time
+	lastIncrease time.Time
+	pausedUntil  time.Time
+}
+
+// NewAdaptiveRateLimiter creates an adaptive limiter starting at the initial rate
+func NewAdaptiveRateLimiter(settings AdaptiveRateLimiterSettings) (*AdaptiveRateLimiter, error) {
+	if settings.Min == 0 {
+		settings.Min = 1
+	}
+	if settings.Max == 0 {
+		settings.Max = rate.Limit(maxRatePerMinute)
+	}
+	if settings.Initial == 0 {
+		settings.Initial = settings.Max
+	}
+	if settings.Burst == 0 {
+		settings.Burst = maxConcurrentConnections
+		if settings.Limiter != nil {
+			settings.Burst = settings.Limiter.Burst()
+		}
+	}
+	if settings.Backoff == 0 {
+		settings.Backoff = 0.5
+	}
+	if settings.Step == 0 {
+		settings.Step = settings.Max / 20
+	}
+	if settings.Interval == 0 {
+		settings.Interval = 10 * time.Second
+	}
+
+	switch {
+	case settings.Min < 0 || settings.Max == rate.Inf:
+		return nil, errors.New("rates must be positive and finite")
+	case settings.Min > settings.Max:
+		return nil, errors.New("min rate must not be greater than max rate")
+	case settings.Initial < settings.Min || settings.Initial > settings.Max:
+		return nil, errors.New("initial rate must be between min and max rates")
+	case settings.Burst < 0:
+		return nil, errors.New("burst cannot be negative")
+	case settings.Backoff <= 0 || settings.Backoff >= 1:
+		return nil, errors.New("backoff must be between 0 and 1")
+	case settings.Step < 0 || settings.Interval < 0:
+		return nil, errors.New("step and interval cannot be negative")
+	}
+
+	limiter := settings.Limiter
+	if limiter == nil {
+		limiter = rate.NewLimiter(settings.Initial, settings.Burst)
+	} else {
+		limiter.SetLimit(settings.Initial)
+		limiter.SetBurst(settings.Burst)
+	}
+
+	return &AdaptiveRateLimiter{
+		limiter:      limiter,
+		settings:     settings,
+		lastIncrease: time.Now(),
+	}, nil
+}
+
+// Limiter returns the underlying limiter. Waiting on it does not honor the pause of a Retry-After, see Wait
+func (a *AdaptiveRateLimiter) Limiter() *rate.Limiter {
+	return a.limiter
+}
+
+// Rate returns the current rate, in requests per second
+func (a *AdaptiveRateLimiter) Rate() rate.Limit {
+	return a.limiter.Limit()
+}
+
+// Throttled decreases the rate multiplicatively. With a retryAfter greater than zero
+// the next requests are also held for that time, up to 5 minutes.
+func (a *AdaptiveRateLimiter) Throttled(retryAfter time.Duration) {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if now.Sub(a.lastDecrease) >= adaptiveDecreaseGap {
+		limit := a.limiter.Limit() * rate.Limit(a.settings.Backoff)
+		if limit < a.settings.Min {
+			limit = a.settings.Min
+		}
+		a.limiter.SetLimitAt(now, limit)
+		a.lastDecrease = now
+	}
+	// the ramp up starts over
+	a.lastIncrease = now
+
+	if retryAfter > maxRetryAfterPause {
+		retryAfter = maxRetryAfterPause
+	}
+	// Wait holds the next requests until then
+	if until := now.Add(retryAfter); until.After(a.pausedUntil) {
+		a.pausedUntil = until
+	}
+}
+
+// Wait blocks until the pause of a Retry-After is over and the limiter permits a request.
+// It fails right away when the pause ends after the context deadline.
+func (a *AdaptiveRateLimiter) Wait(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		until := a.pausedUntil
+		a.mu.Unlock()
+
+		pause := time.Until(until)
+		if pause <= 0 {
+			return a.limiter.Wait(ctx)
+		}
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(until) {
+			return fmt.Errorf("paused for %v by Retry-After, beyond the context deadline", pause.Round(time.Millisecond))
+		}
+		// the pause may have been extended meanwhile
+		if !sleep(ctx, pause) {
+			return ctx.Err()
+		}
+	}
+}
+
+// Succeeded increases the rate additively, once per interval at most
+func (a *AdaptiveRateLimiter) Succeeded() {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if now.Sub(a.lastIncrease) < a.settings.Interval {
+		return
+	}
+	limit := a.limiter.Limit() + a.settings.Step
+	if limit > a.settings.Max {
+		limit = a.settings.Max
+	}
+	a.limiter.SetLimitAt(now, limit)
+	a.lastIncrease = now
+}
+
+// waitRateLimit blocks until the rate limiter permits a request, honoring the pause
+// of the adaptive limiter if any
+func (c *ClientHTTP) waitRateLimit(ctx context.Context) error {
+	if c.adaptive != nil {
+		return c.adaptive.Wait(ctx)
+	}
+	return c.rateLimit.Wait(ctx)
+}
+
+// observe feeds the limiter with the outcome of a request
+func (a *AdaptiveRateLimiter) observe(resp *http.Response, err error) {
+	if err != nil {
+		return
+	}
+
+	apiErr := responseError(resp, nil)
+	wait, hasRetryAfter := retryAfter(apiErr)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || hasRetryAfter:
+		a.Throttled(wait)
+	case apiErr == nil:
+		a.Succeeded()
+	}
+}
+
+// AdaptiveRateLimiting limits the rate of requests with the adaptive limiter,
+// fed with the responses of the client. Along with CustomRateLimiter, the custom
+// limiter must be the one adjusted, see AdaptiveRateLimiterSettings.Limiter.
+func AdaptiveRateLimiting(a *AdaptiveRateLimiter) ClientHTTPOption {
+	return func(o *ClientHTTPOptions) error {
+		if a == nil {
+			return errors.New("adaptive rate limiter is nil")
+		}
+		o.adaptiveRateLimiter = a
+		return nil
+	}
+}
@@ -0,0 +1,230 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestAdaptiveRateLimiter(t *testing.T) {
+	limiter, err := kickbox.NewAdaptiveRateLimiter(kickbox.AdaptiveRateLimiterSettings{
+		Min:      10,
+		Max:      100,
+		Step:     20,
+		Interval: 10 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, rate.Limit(100), limiter.Rate())
+	assert.Equal(t, rate.Limit(100), limiter.Limiter().Limit())
+
+	// multiplicative decrease, once per signal burst
+	limiter.Throttled(0)
+	assert.Equal(t, rate.Limit(50), limiter.Rate())
+	limiter.Throttled(0)
+	assert.Equal(t, rate.Limit(50), limiter.Rate())
+
+	// no increase before the interval
+	limiter.Succeeded()
+	assert.Equal(t, rate.Limit(50), limiter.Rate())
+
+	// additive increase, bounded by the max rate
+	for _, want := range []rate.Limit{70, 90, 100, 100} {
+		time.Sleep(15 * time.Millisecond)
+		limiter.Succeeded()
+		assert.Equal(t, want, limiter.Rate())
+	}
+}
+
+func TestAdaptiveRateLimiterMinRate(t *testing.T) {
+	limiter, err := kickbox.NewAdaptiveRateLimiter(kickbox.AdaptiveRateLimiterSettings{
+		Min:     40,
+		Max:     100,
+		Backoff: 0.3,
+	})
+	assert.Nil(t, err)
+
+	limiter.Throttled(0)
+	assert.Equal(t, rate.Limit(40), limiter.Rate())
+}
+
+func TestAdaptiveRateLimiterRetryAfterPause(t *testing.T) {
+	limiter, err := kickbox.NewAdaptiveRateLimiter(kickbox.AdaptiveRateLimiterSettings{
+		Min: 100,
+		Max: 100,
+	})
+	assert.Nil(t, err)
+
+	limiter.Throttled(50 * time.Millisecond)
+	// a shorter Retry-After does not shorten the pause
+	limiter.Throttled(10 * time.Millisecond)
+
+	// no tokens are taken in advance, only Wait holds the requests
+	assert.True(t, limiter.Limiter().Allow())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, limiter.Wait(ctx), "the pause ends after the deadline")
+
+	start := time.Now()
+	assert.Nil(t, limiter.Wait(context.TODO()))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+}
+
+func TestClientAdaptiveRateLimitingRetryAfter(t *testing.T) {
+	svr, calls := flakyServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"1"}})
+	defer svr.Close()
+
+	limiter, err := kickbox.NewAdaptiveRateLimiter(kickbox.AdaptiveRateLimiterSettings{})
+	assert.Nil(t, err)
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.AdaptiveRateLimiting(limiter),
+	)
+	assert.Nil(t, err)
+
+	_, _, err = client.Verify(context.TODO(), "user@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrRateLimited))
+
+	start := time.Now()
+	_, _, err = client.Verify(context.TODO(), "user@example.com")
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 900*time.Millisecond, "the next request waits for the Retry-After")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestNewAdaptiveRateLimiterErrors(t *testing.T) {
+	tests := []struct {
+		settings kickbox.AdaptiveRateLimiterSettings
+		err      string
+	}{
+		{kickbox.AdaptiveRateLimiterSettings{Max: rate.Inf}, "rates must be positive and finite"},
+		{kickbox.AdaptiveRateLimiterSettings{Min: 10, Max: 5}, "min rate must not be greater than max rate"},
+		{kickbox.AdaptiveRateLimiterSettings{Min: 10, Max: 20, Initial: 30}, "initial rate must be between min and max rates"},
+		{kickbox.AdaptiveRateLimiterSettings{Burst: -1}, "burst cannot be negative"},
+		{kickbox.AdaptiveRateLimiterSettings{Backoff: 1.5}, "backoff must be between 0 and 1"},
+		{kickbox.AdaptiveRateLimiterSettings{Interval: -time.Second}, "step and interval cannot be negative"},
+	}
+	for _, test := range tests {
+		_, err := kickbox.NewAdaptiveRateLimiter(test.settings)
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestClientAdaptiveRateLimiting(t *testing.T) {
+	svr, calls := flakyServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"0"}})
+	defer svr.Close()
+
+	limiter, err := kickbox.NewAdaptiveRateLimiter(kickbox.AdaptiveRateLimiterSettings{
+		Min:      10,
+		Max:      1000,
+		Interval: time.Hour,
+	})
+	assert.Nil(t, err)
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.AdaptiveRateLimiting(limiter),
+	)
+	assert.Nil(t, err)
+
+	_, _, err = client.Verify(context.TODO(), "user@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrRateLimited))
+	assert.Equal(t, rate.Limit(500), limiter.Rate())
+
+	_, _, err = client.Verify(context.TODO(), "user@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	assert.Equal(t, rate.Limit(500), limiter.Rate())
+
+	_, err = kickbox.New("apikey", kickbox.AdaptiveRateLimiting(nil))
+	assert.EqualError(t, err, "applying optional settings: adaptive rate limiter is nil")
+}
+
+func TestClientAdaptiveRateLimitingCustomLimiter(t *testing.T) {
+	custom := rate.NewLimiter(10, 5)
+	limiter, err := kickbox.NewAdaptiveRateLimiter(kickbox.AdaptiveRateLimiterSettings{
+		Max:     50,
+		Limiter: custom,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, custom, limiter.Limiter())
+	assert.Equal(t, rate.Limit(50), custom.Limit())
+	assert.Equal(t, 5, custom.Burst(), "the burst of the custom limiter is kept")
+
+	// the adjusted limiter can be given as custom limiter too, in any order
+	_, err = kickbox.New("apikey", kickbox.CustomRateLimiter(custom), kickbox.AdaptiveRateLimiting(limiter))
+	assert.Nil(t, err)
+	_, err = kickbox.New("apikey", kickbox.AdaptiveRateLimiting(limiter), kickbox.CustomRateLimiter(custom))
+	assert.Nil(t, err)
+
+	// any other limiter would not be adjusted
+	other := rate.NewLimiter(10, 1)
+	for _, opts := range [][]kickbox.ClientHTTPOption{
+		{kickbox.CustomRateLimiter(other), kickbox.AdaptiveRateLimiting(limiter)},
+		{kickbox.AdaptiveRateLimiting(limiter), kickbox.CustomRateLimiter(other)},
+	} {
+		_, err = kickbox.New("apikey", opts...)
+		assert.EqualError(t, err, "applying optional settings: the custom rate limiter is not adjusted by the adaptive rate limiter, see AdaptiveRateLimiterSettings.Limiter")
+	}
+}
+
+func TestClientRateLimiterBurst(t *testing.T) {
+	svr, calls := flakyServer(0, http.StatusOK, nil)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.MaxConcurrentConnections(100),
+	)
+	assert.Nil(t, err)
+
+	// a burst of 1 would space them out over 750ms at the default rate
+	start := time.Now()
+	verifyConcurrently(context.TODO(), t, client, 100)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	assert.Equal(t, int32(100), atomic.LoadInt32(calls))
+}
+
+func TestAdaptiveRateLimiterDefaultBurst(t *testing.T) {
+	svr, calls := flakyServer(0, http.StatusOK, nil)
+	defer svr.Close()
+
+	limiter, err := kickbox.NewAdaptiveRateLimiter(kickbox.AdaptiveRateLimiterSettings{Min: 1, Max: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 25, limiter.Limiter().Burst())
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.AdaptiveRateLimiting(limiter),
+	)
+	assert.Nil(t, err)
+
+	// at 1 request per second, waiting on the limiter would exceed the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	verifyConcurrently(ctx, t, client, 25)
+	assert.Equal(t, int32(25), atomic.LoadInt32(calls))
+}
+
+// verifyConcurrently makes n verifications at once, expecting them to succeed
+func verifyConcurrently(ctx context.Context, t *testing.T, client *kickbox.ClientHTTP, n int) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := client.Verify(ctx, "user@example.com")
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+}
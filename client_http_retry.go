@@ -0,0 +1,182 @@
+package kickbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	MaxAttempts int                  // total number of attempts, including the first one
+	BaseBackoff time.Duration        // wait before the first retry, doubled on every attempt
+	MaxBackoff  time.Duration        // upper limit of the wait between attempts
+	Jitter      float64              // fraction [0, 1] of every wait that is randomized
+	Retryable   func(err error) bool // classifies retryable errors, DefaultRetryable when nil
+}
+
+// defaultRetryPolicy makes a single attempt
+var defaultRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+// WithRetryPolicy enables retrying transient failures with exponential backoff.
+// A Retry-After header sent by the service takes precedence over the computed backoff.
+func WithRetryPolicy(p RetryPolicy) ClientHTTPOption {
+	return func(o *ClientHTTPOptions) error {
+		if p.MaxAttempts <= 0 {
+			return errors.New("max attempts must be greater than zero")
+		}
+		if p.BaseBackoff < 0 || p.MaxBackoff < 0 {
+			return errors.New("backoff cannot be negative")
+		}
+		if p.MaxBackoff != 0 && p.MaxBackoff < p.BaseBackoff {
+			return errors.New("max backoff must be greater than base backoff")
+		}
+		if p.Jitter < 0 || p.Jitter > 1 {
+			return errors.New("jitter must be between 0 and 1")
+		}
+		o.retryPolicy = p
+		return nil
+	}
+}
+
+// DefaultRetryable reports rate limited (429) and server (5xx) responses,
+// network errors and connection resets as retryable
+func DefaultRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return errors.Is(apiErr, ErrRateLimited) || errors.Is(apiErr, ErrServerError)
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// RetryError is returned when a request has been attempted more than once.
+// It holds the error of every attempt, the last one being unwrapped.
+type RetryError struct {
+	Attempts []error
+}
+
+// Error implements the error interface
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts: %v", len(e.Attempts), e.Unwrap())
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RetryError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1]
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return DefaultRetryable(err)
+}
+
+// backoff calculates the wait before the next attempt
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	if d, ok := retryAfter(err); ok {
+		return d
+	}
+
+	d := time.Duration(float64(p.BaseBackoff) * math.Pow(2, float64(attempt-1)))
+	if p.MaxBackoff > 0 && (d > p.MaxBackoff || d < 0) {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(p.Jitter * rand.Float64() * float64(d))
+	}
+	return d
+}
+
+// retryAfter extracts the Retry-After header value from an *APIError
+// see: https://datatracker.ietf.org/doc/html/rfc7231#section-7.1.3
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Header == nil {
+		return 0, false
+	}
+
+	value := apiErr.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// retry calls attempt until it succeeds, the policy gives up or the context
+// cannot fit another attempt. Non replayable calls are attempted only once.
+func retry(ctx context.Context, p RetryPolicy, replayable bool, attempt func(n int) error) error {
+	var attempts []error
+	for n := 1; ; n++ {
+		err := attempt(n)
+		if err == nil {
+			return nil
+		}
+		attempts = append(attempts, err)
+
+		if !replayable || n >= p.MaxAttempts || !p.retryable(err) {
+			break
+		}
+
+		wait := p.backoff(n, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			break
+		}
+
+		if !sleep(ctx, wait) {
+			break
+		}
+	}
+
+	if len(attempts) == 1 {
+		return attempts[0]
+	}
+	return &RetryError{Attempts: attempts}
+}
+
+// sleep waits for the given duration, returns false if the context is done before
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,258 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyOptions(t *testing.T) {
+	optionCases := []struct {
+		policy   kickbox.RetryPolicy
+		expected string
+	}{
+		{
+			policy:   kickbox.RetryPolicy{},
+			expected: "max attempts must be greater than zero",
+		},
+		{
+			policy:   kickbox.RetryPolicy{MaxAttempts: 3, BaseBackoff: -time.Second},
+			expected: "backoff cannot be negative",
+		},
+		{
+			policy:   kickbox.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Second, MaxBackoff: time.Millisecond},
+			expected: "max backoff must be greater than base backoff",
+		},
+		{
+			policy:   kickbox.RetryPolicy{MaxAttempts: 3, Jitter: 1.5},
+			expected: "jitter must be between 0 and 1",
+		},
+		{
+			policy: kickbox.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Second, Jitter: 0.2},
+		},
+	}
+
+	options := kickbox.ClientHTTPOptions{}
+	for _, oc := range optionCases {
+		err := kickbox.WithRetryPolicy(oc.policy)(&options)
+		if oc.expected != "" {
+			assert.EqualError(t, err, oc.expected)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
+
+// flakyServer fails the first failures requests with the given status
+func flakyServer(failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+	var calls int32
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n <= failures {
+			for k, v := range header {
+				rw.Header()[k] = v
+			}
+			rw.WriteHeader(status)
+			_, _ = rw.Write([]byte(`{"success":false,"message":"try again"}`))
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(`{"id":123,"result":"deliverable","status":"completed","success":true}`))
+	}
+	return httptest.NewServer(http.HandlerFunc(handler)), &calls
+}
+
+func TestVerifyRetriesTransientFailures(t *testing.T) {
+	svr, calls := flakyServer(2, http.StatusServiceUnavailable, nil)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond}),
+	)
+	assert.Nil(t, err)
+
+	header, resp, err := client.Verify(context.TODO(), "email@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, header.HTTPStatus)
+	assert.Equal(t, kickbox.ResultDeliverable, resp.Result)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestVerifyRetriesGivingUp(t *testing.T) {
+	svr, calls := flakyServer(10, http.StatusTooManyRequests, nil)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, Jitter: 1}),
+	)
+	assert.Nil(t, err)
+
+	_, _, err = client.Verify(context.TODO(), "email@example.com")
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+	var retryErr *kickbox.RetryError
+	assert.True(t, errors.As(err, &retryErr))
+	assert.Len(t, retryErr.Attempts, 3)
+	for _, attemptErr := range retryErr.Attempts {
+		assert.True(t, errors.Is(attemptErr, kickbox.ErrRateLimited))
+	}
+	assert.True(t, errors.Is(err, kickbox.ErrRateLimited))
+	assert.EqualError(t, err, "giving up after 3 attempts: kickbox api error: status 429: try again")
+}
+
+func TestVerifyRetriesNotRetryable(t *testing.T) {
+	svr, calls := flakyServer(10, http.StatusBadRequest, nil)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 3}),
+	)
+	assert.Nil(t, err)
+
+	_, _, err = client.Verify(context.TODO(), "email@example.com")
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	var apiErr *kickbox.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.HTTPStatus)
+
+	var retryErr *kickbox.RetryError
+	assert.False(t, errors.As(err, &retryErr))
+}
+
+func TestVerifyRetriesCustomClassifier(t *testing.T) {
+	svr, calls := flakyServer(1, http.StatusBadRequest, nil)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{
+			MaxAttempts: 2,
+			Retryable:   func(err error) bool { return true },
+		}),
+	)
+	assert.Nil(t, err)
+
+	_, _, err = client.Verify(context.TODO(), "email@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestVerifyRetriesHonorRetryAfter(t *testing.T) {
+	svr, calls := flakyServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"1"}})
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond}),
+	)
+	assert.Nil(t, err)
+
+	start := time.Now()
+	_, _, err = client.Verify(context.TODO(), "email@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	assert.True(t, time.Since(start) >= time.Second, "Retry-After must be honored")
+}
+
+func TestVerifyRetriesContextDeadline(t *testing.T) {
+	svr, calls := flakyServer(10, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"10"}})
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 5}),
+	)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err = client.Verify(ctx, "email@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrRateLimited))
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	assert.True(t, time.Since(start) < time.Second, "must not wait beyond the context deadline")
+}
+
+func TestVerifyBatchRetriesReplayableFile(t *testing.T) {
+	const csvFilePath = "./testdata/sample.csv"
+
+	expectedFileContent, err := os.ReadFile(csvFilePath)
+	assert.Nil(t, err)
+
+	var calls int32
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		assert.Equal(t, string(expectedFileContent), string(content))
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(`{"id":123,"success":true}`))
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond}),
+	)
+	assert.Nil(t, err)
+
+	emailsFile, err := os.Open(csvFilePath)
+	assert.Nil(t, err)
+
+	resp, err := client.VerifyBatch(context.TODO(), emailsFile)
+	assert.Nil(t, err)
+	assert.Equal(t, 123, resp.ID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestVerifyBatchRetriesNonReplayableBody(t *testing.T) {
+	svr, calls := flakyServer(10, http.StatusServiceUnavailable, nil)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond}),
+	)
+	assert.Nil(t, err)
+
+	_, err = client.VerifyBatch(context.TODO(), io.NopCloser(strings.NewReader("email@example.com\n")))
+	assert.True(t, errors.Is(err, kickbox.ErrServerError))
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestVerifyBatchCheckRetries(t *testing.T) {
+	svr, calls := flakyServer(1, http.StatusInternalServerError, nil)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond}),
+	)
+	assert.Nil(t, err)
+
+	resp, err := client.VerifyBatchCheck(context.TODO(), "123")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchCompleted, resp.Status)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
@@ -12,8 +12,8 @@ import (
 // ResponseVerify kickbox structure on success response (200OK)
 // see: https://docs.kickbox.com/docs/single-verification-api#the-response
 type ResponseVerify struct {
-	Result     string  `json:"result"`       // "undeliverable",
-	Reason     string  `json:"reason"`       // "rejected_email",
+	Result     Result  `json:"result"`       // "undeliverable",
+	Reason     Reason  `json:"reason"`       // "rejected_email",
 	Role       bool    `json:"role"`         // false,
 	Free       bool    `json:"free"`         // false,
 	Disposable bool    `json:"disposable"`   // false,
@@ -30,9 +30,10 @@ type ResponseVerify struct {
 // ResponseVerifyHeaders
 // see: https://docs.kickbox.com/docs/using-the-api#response-headers
 type ResponseVerifyHeaders struct {
-	Balance      int // Your remaining verification credit balance
-	ResponseTime int // The elapsed time (in milliseconds) it took Kickbox to process the request
-	HTTPStatus   int // HTTP Status Response Code
+	Balance      int  // Your remaining verification credit balance
+	ResponseTime int  // The elapsed time (in milliseconds) it took Kickbox to process the request
+	HTTPStatus   int  // HTTP Status Response Code
+	Cached       bool // The response was served by a cache, see WithCache
 }
 
 // VerifyRequestOptions holds the optional parameters for the Verify request
@@ -54,24 +55,16 @@ func Timeout(d time.Duration) VerifyOption {
 
 // Verify calls the verification endpoint
 // Optionaly a timeout can be specified
+// When all the connections are in use it waits for a free one, see FailFastConnections
 func (c *ClientHTTP) Verify(ctx context.Context, email string, opts ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error) {
-	const verifyPath = "/v2/verify"
-
-	// RateLimiter will block until it is permitted or the context is canceled
-	if err := c.rateLimit.Wait(ctx); err != nil {
-		return nil, nil, fmt.Errorf("rate limiting requests: %v", err)
+	// Invalid syntax is answered without calling the API
+	if c.validateSyntax {
+		if err := ValidateSyntax(email); err != nil {
+			header, body := invalidSyntaxResponse(email)
+			return header, body, nil
+		}
 	}
 
-	// MaxConcurrentConnections control
-	select {
-	case c.connPool <- struct{}{}:
-	default:
-		return nil, nil, fmt.Errorf("max connections oppened: %d", maxConcurrentConnections)
-	}
-	defer func() {
-		<-c.connPool
-	}()
-
 	// Default options
 	const defaultRequestTimeout = 6000 * time.Millisecond
 	options := VerifyRequestOptions{
@@ -85,6 +78,56 @@ func (c *ClientHTTP) Verify(ctx context.Context, email string, opts ...VerifyOpt
 		return nil, nil, fmt.Errorf("timeout not valid, must be less than 30 sec: %v", options.timeout)
 	}
 
+	var (
+		header *ResponseVerifyHeaders
+		body   *ResponseVerify
+	)
+	err := retry(ctx, c.retry, true, func(_ int) error {
+		var err error
+		header, body, err = c.verify(ctx, email, options)
+		return err
+	})
+
+	return header, body, err
+}
+
+// invalidSyntaxResponse builds the response of an email rejected by ValidateSyntax.
+// The HTTPStatus is zero as no request was made. The Message is left empty like any
+// successful response, the syntax error is returned by ValidateSyntax.
+func invalidSyntaxResponse(email string) (*ResponseVerifyHeaders, *ResponseVerify) {
+	user, domain := splitEmail(email)
+	return &ResponseVerifyHeaders{}, &ResponseVerify{
+		Result:  ResultUndeliverable,
+		Reason:  ReasonInvalidEmail,
+		Email:   email,
+		User:    user,
+		Domain:  domain,
+		Success: true,
+	}
+}
+
+// verify makes a single request attempt to the verification endpoint. It fails fast while the
+// circuit breaker is open, before waiting for the rate limiter and a free connection, and
+// checks it again once they are through: the breaker may have opened meanwhile.
+// The connection is held only while sending the request, not during the rate limit wait or the retry backoff.
+func (c *ClientHTTP) verify(ctx context.Context, email string, options VerifyRequestOptions) (*ResponseVerifyHeaders, *ResponseVerify, error) {
+	const verifyPath = "/v2/verify"
+
+	if err := c.failFast(); err != nil {
+		return nil, nil, err
+	}
+
+	// RateLimiter will block until it is permitted or the context is canceled
+	if err := c.waitRateLimit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("rate limiting requests: %v", err)
+	}
+
+	// MaxConcurrentConnections control
+	if err := c.acquireConn(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer c.releaseConn()
+
 	ctx, cancel := context.WithTimeout(ctx, options.timeout)
 	defer cancel()
 
@@ -98,13 +141,13 @@ func (c *ClientHTTP) Verify(ctx context.Context, email string, opts ...VerifyOpt
 	// Adds query params
 	q := req.URL.Query()
 	q.Add("email", email)
-	q.Add("apikey", c.apiKey)
 	q.Add("timeout", fmt.Sprintf("%v", options.timeout.Milliseconds()))
 	req.URL.RawQuery = q.Encode()
+	c.authenticate(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, nil, fmt.Errorf("doing request: %v", err)
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
@@ -118,6 +161,10 @@ func (c *ClientHTTP) Verify(ctx context.Context, email string, opts ...VerifyOpt
 		HTTPStatus:   resp.StatusCode,
 	}
 
+	if err := checkResponse(resp); err != nil {
+		return &header, nil, err
+	}
+
 	// Parse the the body response
 	var body ResponseVerify
 	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
@@ -126,3 +173,17 @@ func (c *ClientHTTP) Verify(ctx context.Context, email string, opts ...VerifyOpt
 
 	return &header, &body, nil
 }
+
+// VerifyMany verifies the emails concurrently, respecting the rate limit and the
+// connection pool of the client. The results are streamed through the returned channel,
+// which must be drained or the context canceled.
+func (c *ClientHTTP) VerifyMany(ctx context.Context, emails []string, opts ...VerifyManyOption) <-chan VerifyManyResult {
+	options := newVerifyManyOptions(cap(c.connPool), opts)
+	return verifyMany(ctx, c.Verify, sliceSource(emails), len(emails), options)
+}
+
+// VerifyManyChan is like VerifyMany, reading the emails from a channel until it is closed
+func (c *ClientHTTP) VerifyManyChan(ctx context.Context, emails <-chan string, opts ...VerifyManyOption) <-chan VerifyManyResult {
+	options := newVerifyManyOptions(cap(c.connPool), opts)
+	return verifyMany(ctx, c.Verify, chanSource(emails), -1, options)
+}
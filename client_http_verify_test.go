@@ -3,6 +3,7 @@ package kickbox_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -52,7 +53,7 @@ func TestVerifyBuildingRequestError(t *testing.T) {
 }
 
 func TestVerifyRequestError(t *testing.T) {
-	client, err := kickbox.New("apikey",
+	client, err := kickbox.New("s3cr3t-key",
 		kickbox.OverrideBaseURL("http://nonexistinghost.test.me"),
 	)
 	assert.Nil(t, err)
@@ -61,9 +62,10 @@ func TestVerifyRequestError(t *testing.T) {
 	defer cancel()
 	_, _, err = client.Verify(ctx, "email@example.com")
 	assert.NotNil(t, err)
-	assert.EqualError(t, err,
-		"doing request: Get \"http://nonexistinghost.test.me/v2/verify?apikey=apikey&email=email%40example.com&timeout=6000\": "+
-			"dial tcp: lookup nonexistinghost.test.me: no such host")
+	assert.Contains(t, err.Error(),
+		"doing request: Get \"http://nonexistinghost.test.me/v2/verify?apikey=REDACTED&email=email%40example.com&timeout=6000\": "+
+			"dial tcp: lookup nonexistinghost.test.me")
+	assert.NotContains(t, err.Error(), "s3cr3t-key")
 }
 
 func TestVerifyRequestBodyBroken(t *testing.T) {
@@ -138,16 +140,21 @@ func TestVerifyMaxConcurrentConnections(t *testing.T) {
 	client, err := kickbox.New("apikey",
 		kickbox.OverrideBaseURL(svr.URL),
 		kickbox.MaxConcurrentConnections(25),
+		kickbox.FailFastConnections(),
 	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	errCtrl := make(chan struct{})
+	errCtrl := make(chan error)
 	totalErrors := 0
+	done := make(chan struct{})
 	go func() {
-		for range errCtrl {
+		defer close(done)
+		for err := range errCtrl {
 			totalErrors++
+			assert.True(t, errors.Is(err, kickbox.ErrMaxConnections))
+			assert.EqualError(t, err, "max connections opened: 25")
 		}
 	}()
 
@@ -160,12 +167,13 @@ func TestVerifyMaxConcurrentConnections(t *testing.T) {
 			_, _, err := client.Verify(context.TODO(), "email@example.com")
 			if err != nil {
 				// on error send a signal to count the total number
-				errCtrl <- struct{}{}
+				errCtrl <- err
 			}
 		}()
 	}
 	wg.Wait()
 	close(errCtrl)
+	<-done
 
 	if totalErrors != 1 {
 		t.Errorf("expecting only 1 error, got: %d", totalErrors)
@@ -2,315 +2,178 @@ package kickbox
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
 	"net/http"
-	"regexp"
-	"strings"
+	"sync"
 )
 
-// sandbox responses
+// sandbox responses, Email, User and Domain are filled from the verified email
 // see: https://docs.kickbox.com/docs/sandbox-api
-const (
-	sandboxDeliverable string = `
-	{
-		"result": "deliverable",
-		"reason": "accepted_email",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 1,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+var (
+	sandboxDeliverable = ResponseVerify{
+		Result:  ResultDeliverable,
+		Reason:  ReasonAcceptedEmail,
+		Sendex:  1,
+		Success: true,
 	}
-`
-	sandboxUndeliverable string = `
-	{
-		"result": "undeliverable",
-		"reason": "rejected_email",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxUndeliverable = ResponseVerify{
+		Result:  ResultUndeliverable,
+		Reason:  ReasonRejectedEmail,
+		Success: true,
 	}
-`
-	sandboxInvalidDomain string = `
-	{
-		"result": "undeliverable",
-		"reason": "invalid_domain",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
-	}	
-`
-	sandboxInvalidEmail string = `
-	{
-		"result": "undeliverable",
-		"reason": "invalid_email",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxInvalidDomain = ResponseVerify{
+		Result:  ResultUndeliverable,
+		Reason:  ReasonInvalidDomain,
+		Success: true,
 	}
-`
-
-	sandboxInvalidSMTP string = `
-	{
-		"result": "undeliverable",
-		"reason": "invalid_smtp",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxInvalidEmail = ResponseVerify{
+		Result:  ResultUndeliverable,
+		Reason:  ReasonInvalidEmail,
+		Success: true,
 	}
-`
-
-	sandboxLowQuality string = `
-	{
-		"result": "risky",
-		"reason": "low_quality",
-		"role": false,
-		"free": true,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0.5,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxInvalidSMTP = ResponseVerify{
+		Result:  ResultUndeliverable,
+		Reason:  ReasonInvalidSMTP,
+		Success: true,
 	}
-`
-
-	sandboxAcceptAll = `
-	{
-		"result": "risky",
-		"reason": "low_deliverability",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": true,
-		"sendex": 0.7,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxLowQuality = ResponseVerify{
+		Result:  ResultRisky,
+		Reason:  ReasonLowQuality,
+		Free:    true,
+		Sendex:  0.5,
+		Success: true,
 	}
-`
-
-	sandboxRole = `
-	{
-		"result": "risky",
-		"reason": "low_quality",
-		"role": true,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0.7,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxAcceptAll = ResponseVerify{
+		Result:    ResultRisky,
+		Reason:    ReasonLowDeliverability,
+		AcceptAll: true,
+		Sendex:    0.7,
+		Success:   true,
+	}
+	sandboxRole = ResponseVerify{
+		Result:  ResultRisky,
+		Reason:  ReasonLowQuality,
+		Role:    true,
+		Sendex:  0.7,
+		Success: true,
 	}
-`
-	sandboxDisposable = `
-	{
-		"result": "risky",
-		"reason": "low_quality",
-		"role": false,
-		"free": false,
-		"disposable": true,
-		"accept_all": true,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxDisposable = ResponseVerify{
+		Result:     ResultRisky,
+		Reason:     ReasonLowQuality,
+		Disposable: true,
+		AcceptAll:  true,
+		Success:    true,
 	}
-`
-
-	sandboxTimeout = `
-	{
-		"result": "unknown",
-		"reason": "timeout",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxTimeout = ResponseVerify{
+		Result:  ResultUnknown,
+		Reason:  ReasonTimeout,
+		Success: true,
 	}
-`
-
-	sandboxUnexpectedError = `
-	{
-		"result": "unknown",
-		"reason": "unexpected_error",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxUnexpectedError = ResponseVerify{
+		Result:  ResultUnknown,
+		Reason:  ReasonUnexpectedError,
+		Success: true,
 	}
-`
-
-	sandboxNoConnect = `
-	{
-		"result": "unknown",
-		"reason": "no_connect",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxNoConnect = ResponseVerify{
+		Result:  ResultUnknown,
+		Reason:  ReasonNoConnect,
+		Success: true,
 	}
-`
-
-	sandboxUnavailableSMTP = `
-	{
-		"result": "unknown",
-		"reason": "unavailable_smtp",
-		"role": false,
-		"free": false,
-		"disposable": false,
-		"accept_all": false,
-		"sendex": 0,
-		"email": "%s",
-		"user": "%s",
-		"domain": "%s",
-		"success": true,
-		"message": null
+	sandboxUnavailableSMTP = ResponseVerify{
+		Result:  ResultUnknown,
+		Reason:  ReasonUnavailableSMTP,
+		Success: true,
 	}
-`
-
-	sandboxInsufficientBalance = `
-	{
-		"success": false,
-		"message": "Insufficient balance"
+	sandboxInsufficientBalance = ResponseVerify{
+		Success: false,
+		Message: "Insufficient balance",
 	}
-`
 )
 
 // ClientSandbox is a client for testing without doing external calls
 type ClientSandbox struct {
-	matchList map[*regexp.Regexp]string
+	rules      []SandboxRule
+	batchSteps int
+
+	mu          sync.Mutex
+	nextBatchID int
+	batches     map[int]*sandboxBatch
 }
 
 // Ensure Verifier implementation
 var _ Verifier = (*ClientSandbox)(nil)
 
+// SandboxOption configures the sandbox client
+type SandboxOption func(*ClientSandbox)
+
+// SandboxBatchSteps sets the number of "processing" status checks a batch job goes through
+// before it is completed. Default: 0, jobs are completed right away
+func SandboxBatchSteps(n int) SandboxOption {
+	return func(c *ClientSandbox) {
+		if n >= 0 {
+			c.batchSteps = n
+		}
+	}
+}
+
 // NewSandbox creates a new sandbox client
-func NewSandbox() *ClientSandbox {
-	return &ClientSandbox{
-		matchList: map[*regexp.Regexp]string{
-			regexp.MustCompile(`^deliverable@.+|.+\+deliverable@.+`):                     sandboxDeliverable,
-			regexp.MustCompile(`^undeliverable@.+|.+\+undeliverable@.+`):                 sandboxUndeliverable,
-			regexp.MustCompile(`^invalid\-domain@.+|.+\+invalid\-domain@.+`):             sandboxInvalidDomain,
-			regexp.MustCompile(`^invalid\-email@.+|.+\+invalid\-email@.+`):               sandboxInvalidEmail,
-			regexp.MustCompile(`^invalid\-smtp@.+|.+\+invalid\-smtp@.+`):                 sandboxInvalidSMTP,
-			regexp.MustCompile(`^low\-quality@.+|.+\+low\-quality@.+`):                   sandboxLowQuality,
-			regexp.MustCompile(`^accept\-all@.+|.+\+accept\-all@.+`):                     sandboxAcceptAll,
-			regexp.MustCompile(`^role@.+|.+\+role@.+`):                                   sandboxRole,
-			regexp.MustCompile(`^disposable@.+|.+\+disposable@.+`):                       sandboxDisposable,
-			regexp.MustCompile(`^unexpected\-error@.+|.+\+unexpected\-error@.+`):         sandboxUnexpectedError,
-			regexp.MustCompile(`^timeout@.+|.+\+timeout@.+`):                             sandboxTimeout,
-			regexp.MustCompile(`^no\-connect@.+|.+\+no\-connect@.+`):                     sandboxNoConnect,
-			regexp.MustCompile(`^unavailable\-smtp@.+|.+\+unavailable\-smtp@.+`):         sandboxUnavailableSMTP,
-			regexp.MustCompile(`^insufficient\-balance@.+|.+\+insufficient\-balance@.+`): sandboxInsufficientBalance,
-		},
+func NewSandbox(opts ...SandboxOption) *ClientSandbox {
+	c := &ClientSandbox{
+		nextBatchID: sandboxFirstBatchID,
+		batches:     map[int]*sandboxBatch{},
+		rules:       DefaultSandboxRules(),
+	}
+	for _, apply := range opts {
+		apply(c)
 	}
+	return c
 }
 
-// Verify returns a response depending on the email pattern to be verified.
+// Verify returns a response depending on the first sandbox rule matching the email,
+// deliverable when none matches.
 // this implementation won't call the kickbox api, it's a local sandbox
 // see: https://docs.kickbox.com/docs/sandbox-api
 func (c *ClientSandbox) Verify(_ context.Context, email string, _ ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error) {
-	body := sandboxDeliverable // default
-	for r, b := range c.matchList {
-		if r.MatchString(email) {
-			body = b
+	rule := SandboxRule{
+		Response: sandboxDefaultResponse,
+	}
+	for _, r := range c.rules {
+		if r.Match != nil && r.Match(email) {
+			rule = r
 			break
 		}
 	}
 
-	resp := ResponseVerify{}
-	if err := json.Unmarshal([]byte(body), &resp); err != nil {
-		return nil, nil, fmt.Errorf("(sandbox) unmarshaling body: %v", err)
-	}
-
-	resp.Email = strings.ToLower(email)
-	nameDomain := strings.Split(resp.Email, "@")
-	if len(nameDomain) == 2 {
-		resp.User = nameDomain[0]
-		resp.Domain = nameDomain[1]
-	}
-
 	headers := ResponseVerifyHeaders{
 		Balance:      1,
 		ResponseTime: 1,
 		HTTPStatus:   http.StatusOK,
 	}
+	if rule.Headers != nil {
+		headers = *rule.Headers
+	}
+
+	if rule.Err != nil {
+		return &headers, nil, rule.Err
+	}
+
+	resp := rule.Response
+	resp.Email = EmailKey(email)
+	if user, domain := splitEmail(resp.Email); domain != "" {
+		resp.User = user
+		resp.Domain = domain
+	}
 
 	return &headers, &resp, nil
 }
 
-// VerifyBatch always returns the same response
-func (c *ClientSandbox) VerifyBatch(_ context.Context, _ io.ReadCloser, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
-	const ID = 123456
-	return &ResponseVerifyBatch{
-		ID:      ID,
-		Success: true,
-	}, nil
+// VerifyMany verifies the emails concurrently against the sandbox
+func (c *ClientSandbox) VerifyMany(ctx context.Context, emails []string, opts ...VerifyManyOption) <-chan VerifyManyResult {
+	options := newVerifyManyOptions(maxConcurrentConnections, opts)
+	return verifyMany(ctx, c.Verify, sliceSource(emails), len(emails), options)
 }
 
-func (c *ClientSandbox) VerifyBatchCheck(_ context.Context, _ string) (*VerifyBatchCheckResponse, error) {
-	return nil, errors.New("not implemented")
+// VerifyManyChan is like VerifyMany, reading the emails from a channel until it is closed
+func (c *ClientSandbox) VerifyManyChan(ctx context.Context, emails <-chan string, opts ...VerifyManyOption) <-chan VerifyManyResult {
+	options := newVerifyManyOptions(maxConcurrentConnections, opts)
+	return verifyMany(ctx, c.Verify, chanSource(emails), -1, options)
 }
@@ -0,0 +1,154 @@
+package kickbox
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sandboxFirstBatchID is the ID of the first sandbox batch job, the next ones are consecutive
+const sandboxFirstBatchID = 123456
+
+// sandboxBatch is a batch job verified by the sandbox
+type sandboxBatch struct {
+	id        int
+	name      string
+	createdAt time.Time
+	results   BatchReport
+	checks    int // status checks done
+}
+
+// VerifyBatch verifies every address of the CSV file with the sandbox rules and stores the job.
+// The address is read from the first column, rows without an address are skipped.
+func (c *ClientSandbox) VerifyBatch(ctx context.Context, file io.ReadCloser, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
+	defer file.Close()
+
+	options := VerifyBatchRequestOptions{}
+	for _, apply := range opts {
+		apply(&options)
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var results []ResponseVerify
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("(sandbox) reading file: %v", err)
+		}
+		if len(record) == 0 || !strings.Contains(record[0], "@") {
+			continue
+		}
+
+		_, resp, err := c.Verify(ctx, strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *resp)
+	}
+
+	if len(results) == 0 {
+		return nil, errors.New("(sandbox) no email addresses found")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	batch := &sandboxBatch{
+		id:        c.nextBatchID,
+		name:      options.filename,
+		createdAt: time.Now(),
+		results:   results,
+	}
+	if batch.name == "" {
+		batch.name = "Batch API Process - " + batch.createdAt.UTC().Format("01-02-2006-15-04-05")
+	}
+	c.batches[batch.id] = batch
+	c.nextBatchID++
+
+	return &ResponseVerifyBatch{
+		ID:      batch.id,
+		Success: true,
+	}, nil
+}
+
+// VerifyBatchCheck reports the status of a sandbox batch job. Every check moves the
+// job one step forward: "starting", as many "processing" as SandboxBatchSteps and "completed".
+func (c *ClientSandbox) VerifyBatchCheck(_ context.Context, batchID string) (*VerifyBatchCheckResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	batch, err := c.batch(batchID)
+	if err != nil {
+		return nil, err
+	}
+	batch.checks++
+
+	resp := VerifyBatchCheckResponse{
+		ID:      batch.id,
+		Success: true,
+	}
+
+	total := len(batch.results)
+	switch {
+	case c.batchSteps > 0 && batch.checks == 1:
+		resp.Status = BatchStarting
+	case batch.checks > 1 && batch.checks <= c.batchSteps+1:
+		resp.Status = BatchProcessing
+		processed := total * (batch.checks - 1) / (c.batchSteps + 1)
+		resp.Progress = batch.results.Progress(processed)
+	default:
+		resp.Status = BatchCompleted
+		resp.Name = batch.name
+		resp.CreatedAt = batch.createdAt.UTC().Format("2006-01-02T15:04:05.000Z")
+		resp.DownloadURL = "sandbox://batch/" + strconv.Itoa(batch.id)
+		resp.Stats = batch.results.Stats()
+	}
+
+	return &resp, nil
+}
+
+// DownloadBatchResults serves the results of a completed sandbox batch job
+func (c *ClientSandbox) DownloadBatchResults(_ context.Context, check *VerifyBatchCheckResponse) (*BatchResults, error) {
+	if err := downloadable(check); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	batch, err := c.batch(strconv.Itoa(check.ID))
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(batch.results.WriteCSV(pw))
+	}()
+
+	return ParseBatchResults(pr)
+}
+
+// batch finds a sandbox batch job. Must be called with the lock held.
+func (c *ClientSandbox) batch(batchID string) (*sandboxBatch, error) {
+	id, err := strconv.Atoi(batchID)
+	if err != nil {
+		return nil, fmt.Errorf("(sandbox) batch not found: %s", batchID)
+	}
+
+	batch, found := c.batches[id]
+	if !found {
+		return nil, fmt.Errorf("(sandbox) batch not found: %s", batchID)
+	}
+	return batch, nil
+}
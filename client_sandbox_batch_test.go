@@ -0,0 +1,104 @@
+package kickbox_test
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandboxBatch(t *testing.T) {
+	const file = `email,name
+deliverable@example.com,Bill
+undeliverable@example.com,Peter
+user+risky@example.com,Michael
+role@example.com,Samir
+not an email,Milton
+timeout@example.com,Joanna
+`
+
+	client := kickbox.NewSandbox(kickbox.SandboxBatchSteps(3))
+
+	batch, err := client.VerifyBatch(context.TODO(), io.NopCloser(strings.NewReader(file)), kickbox.Filename("tps-reports.csv"))
+	assert.Nil(t, err)
+	assert.True(t, batch.Success)
+	batchID := strconv.Itoa(batch.ID)
+
+	check, err := client.VerifyBatchCheck(context.TODO(), batchID)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchStarting, check.Status)
+
+	// user+risky is not a sandbox persona, it is deliverable
+	expectedProgress := []kickbox.BatchProgress{
+		{Deliverable: 1, Total: 5, Unprocessed: 4},
+		{Deliverable: 1, Undeliverable: 1, Total: 5, Unprocessed: 3},
+		{Deliverable: 2, Undeliverable: 1, Total: 5, Unprocessed: 2},
+	}
+	for _, expected := range expectedProgress {
+		check, err = client.VerifyBatchCheck(context.TODO(), batchID)
+		assert.Nil(t, err)
+		assert.Equal(t, kickbox.BatchProcessing, check.Status)
+		assert.Equal(t, expected, check.Progress)
+	}
+
+	check, err = client.VerifyBatchCheck(context.TODO(), batchID)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchCompleted, check.Status)
+	assert.Equal(t, "tps-reports.csv", check.Name)
+	assert.InDelta(t, 0.54, check.Stats.Sendex, 0.0001)
+	check.Stats.Sendex = 0
+	assert.Equal(t, kickbox.BatchStats{
+		Deliverable:   2,
+		Undeliverable: 1,
+		Risky:         1,
+		Unknown:       1,
+		Addresses:     5,
+	}, check.Stats)
+
+	results, err := client.DownloadBatchResults(context.TODO(), check)
+	assert.Nil(t, err)
+	defer results.Close()
+
+	var got []string
+	for results.Next() {
+		got = append(got, results.Result().Email+":"+results.Result().Result.String())
+	}
+	assert.Nil(t, results.Err())
+	assert.Equal(t, []string{
+		"deliverable@example.com:deliverable",
+		"undeliverable@example.com:undeliverable",
+		"user+risky@example.com:deliverable",
+		"role@example.com:risky",
+		"timeout@example.com:unknown",
+	}, got)
+}
+
+func TestSandboxBatchErrors(t *testing.T) {
+	client := kickbox.NewSandbox()
+
+	_, err := client.VerifyBatch(context.TODO(), io.NopCloser(strings.NewReader("no emails here\n")))
+	assert.EqualError(t, err, "(sandbox) no email addresses found")
+
+	_, err = client.VerifyBatchCheck(context.TODO(), "abc")
+	assert.EqualError(t, err, "(sandbox) batch not found: abc")
+
+	_, err = client.DownloadBatchResults(context.TODO(), &kickbox.VerifyBatchCheckResponse{
+		ID:          1,
+		Status:      "completed",
+		DownloadURL: "sandbox://batch/1",
+	})
+	assert.EqualError(t, err, "(sandbox) batch not found: 1")
+
+	// jobs without steps are completed on the first check
+	batch, err := client.VerifyBatch(context.TODO(), io.NopCloser(strings.NewReader("a@example.com\nb@example.com\n")))
+	assert.Nil(t, err)
+	check, err := client.VerifyBatchCheck(context.TODO(), strconv.Itoa(batch.ID))
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchCompleted, check.Status)
+	assert.Equal(t, 2, check.Stats.Addresses)
+}
@@ -0,0 +1,129 @@
+package kickbox
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sandboxDefaultResponse is returned when no rule matches
+var sandboxDefaultResponse = sandboxDeliverable
+
+// SandboxMatcher reports whether a sandbox rule applies to the email
+type SandboxMatcher func(email string) bool
+
+// SandboxRule answers the verification of the emails it matches.
+// Rules are evaluated in order, the first matching rule wins.
+type SandboxRule struct {
+	Name     string                 // identifies the rule
+	Match    SandboxMatcher         // decides whether the rule applies
+	Response ResponseVerify         // response returned, Email, User and Domain are filled from the input
+	Headers  *ResponseVerifyHeaders // headers returned, sandbox defaults when nil
+	Err      error                  // returned instead of the response when not nil
+}
+
+// MatchLocalPart matches the emails whose local part is local, case insensitive
+func MatchLocalPart(local string) SandboxMatcher {
+	return func(email string) bool {
+		l, _ := splitEmail(email)
+		return strings.EqualFold(l, local)
+	}
+}
+
+// MatchDomain matches the emails of the domain, case insensitive
+func MatchDomain(domain string) SandboxMatcher {
+	return func(email string) bool {
+		_, d := splitEmail(email)
+		return strings.EqualFold(d, domain)
+	}
+}
+
+// MatchPlusTag matches the emails tagged with tag, e.g. user+tag@example.com
+func MatchPlusTag(tag string) SandboxMatcher {
+	return func(email string) bool {
+		l, _ := splitEmail(email)
+		i := strings.LastIndex(l, "+")
+		return i > 0 && strings.EqualFold(l[i+1:], tag)
+	}
+}
+
+// MatchRegexp matches the emails matching the regular expression
+func MatchRegexp(r *regexp.Regexp) SandboxMatcher {
+	return r.MatchString
+}
+
+// MatchAny matches the emails matched by any of the matchers
+func MatchAny(matchers ...SandboxMatcher) SandboxMatcher {
+	return func(email string) bool {
+		for _, m := range matchers {
+			if m(email) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AddSandboxRules appends rules, evaluated after the existing ones
+func AddSandboxRules(rules ...SandboxRule) SandboxOption {
+	return func(c *ClientSandbox) {
+		c.rules = append(c.rules, rules...)
+	}
+}
+
+// PrependSandboxRules prepends rules, evaluated before the existing ones
+func PrependSandboxRules(rules ...SandboxRule) SandboxOption {
+	return func(c *ClientSandbox) {
+		c.rules = append(append([]SandboxRule{}, rules...), c.rules...)
+	}
+}
+
+// ReplaceSandboxRules replaces the existing rules
+func ReplaceSandboxRules(rules ...SandboxRule) SandboxOption {
+	return func(c *ClientSandbox) {
+		c.rules = append([]SandboxRule{}, rules...)
+	}
+}
+
+// DefaultSandboxRules returns the rules of the kickbox sandbox, each one matching
+// the address used as local part or plus tag, e.g. role@example.com or user+role@example.com
+// see: https://docs.kickbox.com/docs/sandbox-api
+func DefaultSandboxRules() []SandboxRule {
+	personas := []struct {
+		name     string
+		response ResponseVerify
+	}{
+		{"deliverable", sandboxDeliverable},
+		{"undeliverable", sandboxUndeliverable},
+		{"invalid-domain", sandboxInvalidDomain},
+		{"invalid-email", sandboxInvalidEmail},
+		{"invalid-smtp", sandboxInvalidSMTP},
+		{"low-quality", sandboxLowQuality},
+		{"accept-all", sandboxAcceptAll},
+		{"role", sandboxRole},
+		{"disposable", sandboxDisposable},
+		{"unexpected-error", sandboxUnexpectedError},
+		{"timeout", sandboxTimeout},
+		{"no-connect", sandboxNoConnect},
+		{"unavailable-smtp", sandboxUnavailableSMTP},
+		{"insufficient-balance", sandboxInsufficientBalance},
+	}
+
+	rules := make([]SandboxRule, 0, len(personas))
+	for _, p := range personas {
+		rules = append(rules, SandboxRule{
+			Name:     p.name,
+			Match:    MatchAny(MatchLocalPart(p.name), MatchPlusTag(p.name)),
+			Response: p.response,
+		})
+	}
+	return rules
+}
+
+// splitEmail splits the email by its last @
+func splitEmail(email string) (string, string) {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return email, ""
+	}
+	return email[:i], email[i+1:]
+}
@@ -0,0 +1,128 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandboxMatchers(t *testing.T) {
+	tests := []struct {
+		matcher  kickbox.SandboxMatcher
+		email    string
+		expected bool
+	}{
+		{kickbox.MatchLocalPart("role"), "role@example.com", true},
+		{kickbox.MatchLocalPart("role"), "Role@example.com", true},
+		{kickbox.MatchLocalPart("role"), "user+role@example.com", false},
+		{kickbox.MatchDomain("acme.test"), "anyone@ACME.test", true},
+		{kickbox.MatchDomain("acme.test"), "anyone@sub.acme.test", false},
+		{kickbox.MatchPlusTag("vip"), "user+vip@example.com", true},
+		{kickbox.MatchPlusTag("vip"), "user+other+vip@example.com", true},
+		{kickbox.MatchPlusTag("vip"), "+vip@example.com", false},
+		{kickbox.MatchPlusTag("vip"), "user+vip.more@example.com", false},
+		{kickbox.MatchRegexp(regexp.MustCompile(`^\d+@`)), "123@example.com", true},
+		{kickbox.MatchAny(kickbox.MatchLocalPart("a"), kickbox.MatchLocalPart("b")), "b@example.com", true},
+		{kickbox.MatchAny(), "b@example.com", false},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, tc.matcher(tc.email), tc.email)
+	}
+}
+
+func TestSandboxCustomRules(t *testing.T) {
+	errBlocked := errors.New("blocked")
+
+	client := kickbox.NewSandbox(
+		kickbox.AddSandboxRules(kickbox.SandboxRule{
+			Name:     "acme is accept all",
+			Match:    kickbox.MatchDomain("acme.test"),
+			Response: kickbox.ResponseVerify{Result: "risky", Reason: "low_deliverability", AcceptAll: true, Sendex: 0.6, Success: true},
+		}),
+		kickbox.PrependSandboxRules(
+			kickbox.SandboxRule{
+				Name:  "vip",
+				Match: kickbox.MatchPlusTag("vip"),
+				Response: kickbox.ResponseVerify{
+					Result:  "deliverable",
+					Reason:  "accepted_email",
+					Sendex:  1,
+					Success: true,
+				},
+				Headers: &kickbox.ResponseVerifyHeaders{Balance: 99, ResponseTime: 5, HTTPStatus: http.StatusOK},
+			},
+			kickbox.SandboxRule{
+				Name:    "blocked",
+				Match:   kickbox.MatchLocalPart("blocked"),
+				Err:     errBlocked,
+				Headers: &kickbox.ResponseVerifyHeaders{HTTPStatus: http.StatusForbidden},
+			},
+		),
+	)
+
+	// custom rule appended after the sandbox personas
+	_, resp, err := client.Verify(context.TODO(), "Wile.E@Acme.test")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultRisky, resp.Result)
+	assert.True(t, resp.AcceptAll)
+	assert.Equal(t, "wile.e@acme.test", resp.Email)
+	assert.Equal(t, "wile.e", resp.User)
+	assert.Equal(t, "acme.test", resp.Domain)
+
+	_, resp, err = client.Verify(context.TODO(), "undeliverable@acme.test")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultUndeliverable, resp.Result, "personas are evaluated first")
+
+	// prepended rules win over the personas
+	headers, resp, err := client.Verify(context.TODO(), "undeliverable+vip@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultDeliverable, resp.Result)
+	assert.Equal(t, 99, headers.Balance)
+
+	headers, resp, err = client.Verify(context.TODO(), "blocked@example.com")
+	assert.Equal(t, errBlocked, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, http.StatusForbidden, headers.HTTPStatus)
+}
+
+func TestSandboxReplaceRules(t *testing.T) {
+	client := kickbox.NewSandbox(kickbox.ReplaceSandboxRules(kickbox.SandboxRule{
+		Name:     "everything unknown",
+		Match:    func(email string) bool { return !strings.HasSuffix(email, "@example.com") },
+		Response: kickbox.ResponseVerify{Result: "unknown", Reason: "timeout", Success: true},
+	}))
+
+	// personas are gone, unmatched emails are deliverable
+	_, resp, err := client.Verify(context.TODO(), "undeliverable@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultDeliverable, resp.Result)
+
+	_, resp, err = client.Verify(context.TODO(), "user@example.org")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultUnknown, resp.Result)
+}
+
+func TestSandboxRulesOrderIsDeterministic(t *testing.T) {
+	client := kickbox.NewSandbox(kickbox.AddSandboxRules(
+		kickbox.SandboxRule{Match: kickbox.MatchDomain("example.com"), Response: kickbox.ResponseVerify{Result: "first"}},
+		kickbox.SandboxRule{Match: kickbox.MatchDomain("example.com"), Response: kickbox.ResponseVerify{Result: "second"}},
+	))
+
+	for i := 0; i < 100; i++ {
+		_, resp, err := client.Verify(context.TODO(), "user@example.com")
+		assert.Nil(t, err)
+		assert.Equal(t, kickbox.Result("first"), resp.Result)
+	}
+
+	rules := kickbox.DefaultSandboxRules()
+	assert.Len(t, rules, 14)
+	assert.Equal(t, "deliverable", rules[0].Name)
+}
@@ -2,7 +2,6 @@ package kickbox
 
 import (
 	"context"
-	"encoding/json"
 	"strings"
 	"testing"
 
@@ -11,120 +10,120 @@ import (
 
 func TestSandboxResponses(t *testing.T) {
 	tests := []struct {
-		email        string
-		expectedBody string
+		email    string
+		expected ResponseVerify
 	}{
 		{
-			email:        "deliverable@example.com",
-			expectedBody: sandboxDeliverable,
+			email:    "deliverable@example.com",
+			expected: sandboxDeliverable,
 		},
 		{
-			email:        "user+deliverable@example.com",
-			expectedBody: sandboxDeliverable,
+			email:    "user+deliverable@example.com",
+			expected: sandboxDeliverable,
 		},
 		{
-			email:        "undeliverable@example.com",
-			expectedBody: sandboxUndeliverable,
+			email:    "undeliverable@example.com",
+			expected: sandboxUndeliverable,
 		},
 		{
-			email:        "user+undeliverable@example.com",
-			expectedBody: sandboxUndeliverable,
+			email:    "user+undeliverable@example.com",
+			expected: sandboxUndeliverable,
 		},
 		{
-			email:        "invalid-domain@example.com",
-			expectedBody: sandboxInvalidDomain,
+			email:    "invalid-domain@example.com",
+			expected: sandboxInvalidDomain,
 		},
 		{
-			email:        "user+invalid-domain@example.com",
-			expectedBody: sandboxInvalidDomain,
+			email:    "user+invalid-domain@example.com",
+			expected: sandboxInvalidDomain,
 		},
 		{
-			email:        "invalid-email@example.com",
-			expectedBody: sandboxInvalidEmail,
+			email:    "invalid-email@example.com",
+			expected: sandboxInvalidEmail,
 		},
 		{
-			email:        "user+invalid-email@example.com",
-			expectedBody: sandboxInvalidEmail,
+			email:    "user+invalid-email@example.com",
+			expected: sandboxInvalidEmail,
 		},
 		{
-			email:        "invalid-smtp@example.com",
-			expectedBody: sandboxInvalidSMTP,
+			email:    "invalid-smtp@example.com",
+			expected: sandboxInvalidSMTP,
 		},
 		{
-			email:        "user+invalid-smtp@example.com",
-			expectedBody: sandboxInvalidSMTP,
+			email:    "user+invalid-smtp@example.com",
+			expected: sandboxInvalidSMTP,
 		},
 		{
-			email:        "low-quality@example.com",
-			expectedBody: sandboxLowQuality,
+			email:    "low-quality@example.com",
+			expected: sandboxLowQuality,
 		},
 		{
-			email:        "user+low-quality@example.com",
-			expectedBody: sandboxLowQuality,
+			email:    "user+low-quality@example.com",
+			expected: sandboxLowQuality,
 		},
 		{
-			email:        "accept-all@example.com",
-			expectedBody: sandboxAcceptAll,
+			email:    "accept-all@example.com",
+			expected: sandboxAcceptAll,
 		},
 		{
-			email:        "user+accept-all@example.com",
-			expectedBody: sandboxAcceptAll,
+			email:    "user+accept-all@example.com",
+			expected: sandboxAcceptAll,
 		},
 		{
-			email:        "role@example.com",
-			expectedBody: sandboxRole,
+			email:    "role@example.com",
+			expected: sandboxRole,
 		},
 		{
-			email:        "user+role@example.com",
-			expectedBody: sandboxRole,
+			email:    "user+role@example.com",
+			expected: sandboxRole,
 		},
 		{
-			email:        "disposable@example.com",
-			expectedBody: sandboxDisposable,
+			email:    "disposable@example.com",
+			expected: sandboxDisposable,
 		},
 		{
-			email:        "user+disposable@example.com",
-			expectedBody: sandboxDisposable,
+			email:    "user+disposable@example.com",
+			expected: sandboxDisposable,
 		},
 		{
-			email:        "timeout@example.com",
-			expectedBody: sandboxTimeout,
+			email:    "timeout@example.com",
+			expected: sandboxTimeout,
 		},
 		{
-			email:        "user+timeout@example.com",
-			expectedBody: sandboxTimeout,
+			email:    "user+timeout@example.com",
+			expected: sandboxTimeout,
 		},
 		{
-			email:        "unexpected-error@example.com",
-			expectedBody: sandboxUnexpectedError,
+			email:    "unexpected-error@example.com",
+			expected: sandboxUnexpectedError,
 		},
 		{
-			email:        "user+unexpected-error@example.com",
-			expectedBody: sandboxUnexpectedError,
+			email:    "user+unexpected-error@example.com",
+			expected: sandboxUnexpectedError,
 		},
 		{
-			email:        "no-connect@example.com",
-			expectedBody: sandboxNoConnect,
+			email:    "no-connect@example.com",
+			expected: sandboxNoConnect,
 		},
 		{
-			email:        "user+no-connect@example.com",
-			expectedBody: sandboxNoConnect,
+			email:    "user+no-connect@example.com",
+			expected: sandboxNoConnect,
 		},
 		{
-			email:        "unavailable-smtp@example.com",
-			expectedBody: sandboxUnavailableSMTP,
+			email:    "unavailable-smtp@example.com",
+			expected: sandboxUnavailableSMTP,
 		},
 		{
-			email:        "user+unavailable-smtp@example.com",
-			expectedBody: sandboxUnavailableSMTP,
+			email:    "user+unavailable-smtp@example.com",
+			expected: sandboxUnavailableSMTP,
 		},
 		{
-			email:        "insufficient-balance@example.com",
-			expectedBody: sandboxInsufficientBalance,
+			email:    "insufficient-balance@example.com",
+			expected: sandboxInsufficientBalance,
 		},
 		{
-			email:        "user+insufficient-balance@example.com",
-			expectedBody: sandboxInsufficientBalance,
+			email:    "user+insufficient-balance@example.com",
+			expected: sandboxInsufficientBalance,
 		},
 	}
 
@@ -133,8 +132,7 @@ func TestSandboxResponses(t *testing.T) {
 		_, resp, err := c.Verify(context.TODO(), ucase.email)
 		assert.Nil(t, err, "unexpected error")
 
-		expectedResp := ResponseVerify{}
-		_ = json.Unmarshal([]byte(ucase.expectedBody), &expectedResp)
+		expectedResp := ucase.expected
 		expectedResp.Email = ucase.email
 		userDomain := strings.Split(ucase.email, "@")
 		expectedResp.User = userDomain[0]
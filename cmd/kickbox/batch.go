@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+)
+
+const batchUsage = "[flags] <submit|status|wait|download> ..."
+
+// batch runs the batch subcommands
+func (c *cli) batch(ctx context.Context, args []string) (int, error) {
+	fs := c.flagSet("kickbox batch", batchUsage)
+	if err := parseArgs(fs, args, 1); err != nil {
+		return 0, err
+	}
+
+	switch command, rest := fs.Arg(0), fs.Args()[1:]; command {
+	case "submit":
+		return exitOK, c.batchSubmit(ctx, rest)
+	case "status":
+		return exitOK, c.batchStatus(ctx, rest)
+	case "wait":
+		return exitOK, c.batchWait(ctx, rest)
+	case "download":
+		return exitOK, c.batchDownload(ctx, rest)
+	default:
+		fmt.Fprintf(c.stderr, "kickbox: unknown batch command %q\n", command)
+		fs.Usage()
+		return 0, errUsage
+	}
+}
+
+// errSandboxBatch is returned by the batch commands looking for a job submitted
+// by an earlier run with --sandbox, which only existed in the memory of that run
+var errSandboxBatch = errors.New("sandbox batch jobs only exist while the submitting command runs, status, wait and download need the api")
+
+// checkSandboxBatch fails with --sandbox unless the sandbox is shared with the run submitting the job
+func (c *cli) checkSandboxBatch() error {
+	if c.useSandbox && c.sandbox == nil {
+		return errSandboxBatch
+	}
+	return nil
+}
+
+// batchSubmit uploads a CSV file, the emails in its first column
+func (c *cli) batchSubmit(ctx context.Context, args []string) error {
+	fs := c.flagSet("kickbox batch submit", "[flags] <file.csv>")
+	filename := fs.String("filename", "", "name of the batch job, Default: Batch API Process - <date>")
+	callback := fs.String("callback", "", "url called when the batch job is completed")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	out, err := c.output()
+	if err != nil {
+		return err
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var opts []kickbox.VerifyBatchOption
+	if *filename != "" {
+		opts = append(opts, kickbox.Filename(*filename))
+	}
+	if *callback != "" {
+		opts = append(opts, kickbox.Callback(*callback))
+	}
+
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+
+	resp, err := cl.VerifyBatch(ctx, file, opts...)
+	if err != nil {
+		return fmt.Errorf("submitting batch: %w", err)
+	}
+	if err := out.submitted(resp); err != nil {
+		return err
+	}
+	return out.flush()
+}
+
+// batchStatus prints the status of a batch job
+func (c *cli) batchStatus(ctx context.Context, args []string) error {
+	fs := c.flagSet("kickbox batch status", "[flags] <id>")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	if err := c.checkSandboxBatch(); err != nil {
+		return err
+	}
+
+	out, err := c.output()
+	if err != nil {
+		return err
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+
+	resp, err := cl.VerifyBatchCheck(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("checking batch: %w", err)
+	}
+	if err := out.status(resp); err != nil {
+		return err
+	}
+	return out.flush()
+}
+
+// batchWait waits for a batch job to complete, printing the progress to stderr
+func (c *cli) batchWait(ctx context.Context, args []string) error {
+	fs := c.flagSet("kickbox batch wait", "[flags] <id>")
+	interval := fs.Duration("interval", 5*time.Second, "time between status checks")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	if err := c.checkSandboxBatch(); err != nil {
+		return err
+	}
+
+	out, err := c.output()
+	if err != nil {
+		return err
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+
+	resp, err := cl.WaitForBatch(ctx, fs.Arg(0),
+		kickbox.PollInterval(*interval),
+		kickbox.OnBatchProgress(func(p kickbox.BatchProgress) {
+			fmt.Fprintf(c.stderr, "kickbox: batch %s: %d/%d processed\n", fs.Arg(0), p.Total-p.Unprocessed, p.Total)
+		}),
+	)
+	if resp != nil {
+		if err := out.status(resp); err != nil {
+			return err
+		}
+		if err := out.flush(); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// batchDownload prints the results of a completed batch job
+func (c *cli) batchDownload(ctx context.Context, args []string) error {
+	fs := c.flagSet("kickbox batch download", "[flags] <id>")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	if err := c.checkSandboxBatch(); err != nil {
+		return err
+	}
+
+	out, err := c.output()
+	if err != nil {
+		return err
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+
+	check, err := cl.VerifyBatchCheck(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("checking batch: %w", err)
+	}
+	results, err := cl.DownloadBatchResults(ctx, check)
+	if err != nil {
+		return fmt.Errorf("downloading batch: %w", err)
+	}
+	defer results.Close()
+
+	for results.Next() {
+		if err := out.verification(&results.Result().ResponseVerify); err != nil {
+			return err
+		}
+	}
+	if err := results.Err(); err != nil {
+		return fmt.Errorf("reading batch results: %w", err)
+	}
+	return out.flush()
+}
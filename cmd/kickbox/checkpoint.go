@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/wakumaku/kickbox"
+)
+
+// checkpoint stores the verified emails of a verify-file run, so an interrupted
+// run resumes without verifying them again. It is a JSONL file appended on every result.
+type checkpoint struct {
+	path    string
+	file    *os.File
+	enc     *json.Encoder
+	results map[string]*kickbox.ResponseVerify
+}
+
+// checkpointRecord is a line of the checkpoint file
+type checkpointRecord struct {
+	Email    string                  `json:"email"` // email key, see kickbox.EmailKey
+	Response *kickbox.ResponseVerify `json:"response"`
+}
+
+// openCheckpoint loads the results of a previous run, if any, and opens the file for appending.
+// A truncated last line, left by an interrupted write, is dropped.
+func openCheckpoint(path string) (*checkpoint, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening checkpoint: %v", err)
+	}
+
+	cp := &checkpoint{path: path, file: file, results: map[string]*kickbox.ResponseVerify{}}
+	if err := cp.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	cp.enc = json.NewEncoder(file)
+	return cp, nil
+}
+
+// load reads the records and leaves the file positioned after the last complete one
+func (cp *checkpoint) load() error {
+	reader := bufio.NewReader(cp.file)
+	var offset int64
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// incomplete last line, if any, is overwritten
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading checkpoint: %v", err)
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			var record checkpointRecord
+			if err := json.Unmarshal(trimmed, &record); err != nil || record.Response == nil {
+				if err == nil {
+					err = errors.New("missing response")
+				}
+				return fmt.Errorf("reading checkpoint %s: line %d: %v", cp.path, n, err)
+			}
+			cp.results[record.Email] = record.Response
+		}
+		offset += int64(len(line))
+	}
+
+	if err := cp.file.Truncate(offset); err != nil {
+		return fmt.Errorf("truncating checkpoint: %v", err)
+	}
+	if _, err := cp.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking checkpoint: %v", err)
+	}
+	return nil
+}
+
+// result returns the stored response of the email key
+func (cp *checkpoint) result(key string) (*kickbox.ResponseVerify, bool) {
+	resp, ok := cp.results[key]
+	return resp, ok
+}
+
+// add stores the response of the email key
+func (cp *checkpoint) add(key string, resp *kickbox.ResponseVerify) error {
+	if err := cp.enc.Encode(checkpointRecord{Email: key, Response: resp}); err != nil {
+		return fmt.Errorf("writing checkpoint: %v", err)
+	}
+	cp.results[key] = resp
+	return nil
+}
+
+// Close closes the checkpoint file, keeping it for the next run
+func (cp *checkpoint) Close() error {
+	return cp.file.Close()
+}
+
+// remove closes and deletes the checkpoint file, once the run is complete
+func (cp *checkpoint) remove() error {
+	cp.file.Close()
+	return os.Remove(cp.path)
+}
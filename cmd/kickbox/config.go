@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the content of the config file, in YAML or JSON:
+//
+//	api_key: live_1234
+//	base_url: https://api.eu.kickbox.com
+type config struct {
+	APIKey  string `yaml:"api_key"`
+	BaseURL string `yaml:"base_url"`
+}
+
+// config resolves the configuration: flags first, then the environment and the config file
+func (c *cli) config() (*config, error) {
+	cfg, err := c.readConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if key := c.getenv("KICKBOX_API_KEY"); key != "" {
+		cfg.APIKey = key
+	}
+	if c.apiKey != "" {
+		cfg.APIKey = c.apiKey
+	}
+	if c.baseURL != "" {
+		cfg.BaseURL = c.baseURL
+	}
+	return cfg, nil
+}
+
+// readConfig reads the config file. A missing file is an error only when its path was given.
+func (c *cli) readConfig() (*config, error) {
+	path, explicit := c.configPath, true
+	if path == "" {
+		path = c.getenv("KICKBOX_CONFIG")
+	}
+	if path == "" {
+		explicit = false
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return &config{}, nil
+		}
+		path = filepath.Join(dir, "kickbox", "config.yaml")
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %v", err)
+	}
+
+	cfg := &config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("decoding config %s: %v", path, err)
+	}
+	return cfg, nil
+}
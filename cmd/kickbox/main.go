@@ -0,0 +1,190 @@
+// Command kickbox verifies email addresses with the kickbox API.
+//
+//	kickbox [flags] verify <email>...
+//	kickbox [flags] verify-file [--column email] --out <results.jsonl|results.csv> <input.csv|input.jsonl>
+//	kickbox [flags] batch submit [--filename name] [--callback url] <file.csv>
+//	kickbox [flags] batch status <id>
+//	kickbox [flags] batch wait [--interval d] <id>
+//	kickbox [flags] batch download <id>
+//
+// The api key is read from the --api-key flag, the KICKBOX_API_KEY environment
+// variable or the config file, in that order. The exit code of verify reflects
+// the worst result: 0 deliverable, 3 risky, 4 unknown and 5 undeliverable.
+// Errors exit with 1 and usage errors with 2. With --sandbox the batch jobs
+// only exist while batch submit runs.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+)
+
+// Exit codes
+const (
+	exitOK            = 0
+	exitError         = 1
+	exitUsage         = 2
+	exitRisky         = 3
+	exitUnknown       = 4
+	exitUndeliverable = 5
+)
+
+// client is the kickbox client used by the commands, implemented by
+// *kickbox.ClientHTTP and *kickbox.ClientSandbox
+type client interface {
+	kickbox.Verifier
+	WaitForBatch(ctx context.Context, batchID string, opts ...kickbox.WaitForBatchOption) (*kickbox.VerifyBatchCheckResponse, error)
+	VerifyMany(ctx context.Context, emails []string, opts ...kickbox.VerifyManyOption) <-chan kickbox.VerifyManyResult
+	DownloadBatchResults(ctx context.Context, check *kickbox.VerifyBatchCheckResponse) (*kickbox.BatchResults, error)
+}
+
+// errUsage reports a wrong invocation, the usage has already been printed
+var errUsage = errors.New("usage")
+
+// cli holds the environment of a command run
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+
+	// sandbox answers the requests when --sandbox is set, a new one is created when nil.
+	// Tests share it between runs, the batch jobs of a new one are lost on exit.
+	sandbox *kickbox.ClientSandbox
+
+	// global flags
+	apiKey     string
+	configPath string
+	useSandbox bool
+	format     string
+	baseURL    string
+	timeout    time.Duration
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := &cli{stdout: os.Stdout, stderr: os.Stderr, getenv: os.Getenv}
+	os.Exit(c.run(ctx, os.Args[1:]))
+}
+
+// run executes the command line and returns the exit code
+func (c *cli) run(ctx context.Context, args []string) int {
+	fs := c.flagSet("kickbox", "[flags] <verify|verify-file|batch> ...")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var code int
+	var err error
+	switch command, rest := fs.Arg(0), fs.Args()[1:]; command {
+	case "verify":
+		code, err = c.verify(ctx, rest)
+	case "verify-file":
+		code, err = c.verifyFile(ctx, rest)
+	case "batch":
+		code, err = c.batch(ctx, rest)
+	default:
+		fmt.Fprintf(c.stderr, "kickbox: unknown command %q\n", command)
+		fs.Usage()
+		return exitUsage
+	}
+
+	switch {
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case err != nil:
+		fmt.Fprintf(c.stderr, "kickbox: %v\n", err)
+		return exitError
+	}
+	return code
+}
+
+// flagSet creates a flag set with the global flags, which are accepted by every command
+func (c *cli) flagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&c.apiKey, "api-key", c.apiKey, "kickbox api key, Default: $KICKBOX_API_KEY or the config file")
+	fs.StringVar(&c.configPath, "config", c.configPath, "config file, Default: $KICKBOX_CONFIG or <user config dir>/kickbox/config.yaml")
+	fs.BoolVar(&c.useSandbox, "sandbox", c.useSandbox, "answer with the local sandbox, no api calls are made")
+	fs.StringVar(&c.format, "format", c.format, "output format: table, json or csv, Default: table")
+	fs.StringVar(&c.baseURL, "base-url", c.baseURL, "api base url, Default: "+kickbox.BaseURL)
+	fs.DurationVar(&c.timeout, "timeout", c.timeout, "overall time limit, Default: none")
+	return fs
+}
+
+// parseArgs parses the flags of a command, printing the usage when the number of
+// arguments is below min
+func parseArgs(fs *flag.FlagSet, args []string, min int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() < min {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// client creates the client for the global flags and configuration
+func (c *cli) client() (client, error) {
+	if c.useSandbox {
+		if c.sandbox == nil {
+			c.sandbox = kickbox.NewSandbox()
+		}
+		return c.sandbox, nil
+	}
+
+	cfg, err := c.config()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.APIKey == "" {
+		return nil, errors.New("api key not found: use --api-key, $KICKBOX_API_KEY or the config file")
+	}
+
+	var opts []kickbox.ClientHTTPOption
+	if cfg.BaseURL != "" {
+		opts = append(opts, kickbox.OverrideBaseURL(cfg.BaseURL))
+	}
+	return kickbox.New(cfg.APIKey, opts...)
+}
+
+// output creates the writer for the --format flag
+func (c *cli) output() (output, error) {
+	switch strings.ToLower(c.format) {
+	case "", "table":
+		return newTableOutput(c.stdout), nil
+	case "json":
+		return newJSONOutput(c.stdout), nil
+	case "csv":
+		return newCSVOutput(c.stdout), nil
+	}
+	return nil, fmt.Errorf("unknown format %q, expecting table, json or csv", c.format)
+}
+
+// context limits the context to the --timeout flag
+func (c *cli) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+	"github.com/wakumaku/kickbox/kickboxtest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCLI returns a cli writing to buffers with the given environment,
+// sandbox runs share the same sandbox
+func newTestCLI(env map[string]string, sandbox *kickbox.ClientSandbox) (*cli, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	c := &cli{
+		stdout:  stdout,
+		stderr:  stderr,
+		getenv:  func(key string) string { return env[key] },
+		sandbox: sandbox,
+	}
+	return c, stdout, stderr
+}
+
+func TestVerifyExitCodes(t *testing.T) {
+	tests := []struct {
+		emails []string
+		code   int
+	}{
+		{[]string{"deliverable@example.com"}, exitOK},
+		{[]string{"role@example.com"}, exitRisky},
+		{[]string{"timeout@example.com"}, exitUnknown},
+		{[]string{"undeliverable@example.com"}, exitUndeliverable},
+		// the worst result wins
+		{[]string{"deliverable@example.com", "undeliverable@example.com", "role@example.com"}, exitUndeliverable},
+		{[]string{"deliverable@example.com", "insufficient-balance@example.com"}, exitError},
+	}
+	for _, test := range tests {
+		c, _, _ := newTestCLI(nil, nil)
+		code := c.run(context.TODO(), append([]string{"--sandbox", "verify"}, test.emails...))
+		assert.Equal(t, test.code, code, test.emails)
+	}
+}
+
+func TestVerifyFormats(t *testing.T) {
+	c, stdout, _ := newTestCLI(nil, nil)
+	assert.Equal(t, exitOK, c.run(context.TODO(), []string{"--sandbox", "--format", "csv", "verify", "deliverable@example.com"}))
+	assert.Equal(t, "email,result,reason,sendex,role,free,disposable,accept_all,did_you_mean,message\n"+
+		"deliverable@example.com,deliverable,accepted_email,1,false,false,false,false,,\n", stdout.String())
+
+	c, stdout, _ = newTestCLI(nil, nil)
+	assert.Equal(t, exitRisky, c.run(context.TODO(), []string{"verify", "--sandbox", "--format", "json", "role@example.com"}))
+	var resp kickbox.ResponseVerify
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &resp))
+	assert.Equal(t, kickbox.ResultRisky, resp.Result)
+	assert.True(t, resp.Role)
+
+	c, stdout, _ = newTestCLI(nil, nil)
+	assert.Equal(t, exitOK, c.run(context.TODO(), []string{"--sandbox", "verify", "deliverable@example.com"}))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "EMAIL "))
+	assert.Equal(t, []string{"deliverable@example.com", "deliverable", "accepted_email"}, strings.Fields(lines[1])[:3])
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"unknown"},
+		{"verify"},
+		{"--unknown-flag", "verify", "deliverable@example.com"},
+		{"batch"},
+		{"batch", "unknown"},
+		{"batch", "status"},
+	}
+	for _, args := range tests {
+		c, _, stderr := newTestCLI(nil, nil)
+		assert.Equal(t, exitUsage, c.run(context.TODO(), args), args)
+		assert.Contains(t, stderr.String(), "Usage:", args)
+	}
+
+	c, _, stderr := newTestCLI(nil, nil)
+	assert.Equal(t, exitError, c.run(context.TODO(), []string{"--sandbox", "--format", "xml", "verify", "deliverable@example.com"}))
+	assert.Contains(t, stderr.String(), `unknown format "xml"`)
+}
+
+func TestBatchCommands(t *testing.T) {
+	sandbox := kickbox.NewSandbox(kickbox.SandboxBatchSteps(1))
+
+	c, stdout, _ := newTestCLI(nil, sandbox)
+	assert.Equal(t, exitOK, c.run(context.TODO(), []string{"--sandbox", "--format", "json", "batch", "submit", "--filename", "test", "../../testdata/sample.csv"}))
+	var submitted kickbox.ResponseVerifyBatch
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &submitted))
+	assert.True(t, submitted.Success)
+	id := strconv.Itoa(submitted.ID)
+
+	// not completed yet
+	c, stdout, _ = newTestCLI(nil, sandbox)
+	assert.Equal(t, exitOK, c.run(context.TODO(), []string{"--sandbox", "--format", "csv", "batch", "status", id}))
+	assert.Contains(t, stdout.String(), id+",starting,")
+
+	c, stdout, _ = newTestCLI(nil, sandbox)
+	assert.Equal(t, exitOK, c.run(context.TODO(), []string{"--sandbox", "--format", "csv", "batch", "wait", "--interval", "1ms", id}))
+	assert.Contains(t, stdout.String(), id+",completed,15,15,15,0,0,0,")
+
+	c, stdout, _ = newTestCLI(nil, sandbox)
+	assert.Equal(t, exitOK, c.run(context.TODO(), []string{"--sandbox", "--format", "csv", "batch", "download", id}))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Len(t, lines, 16)
+	assert.Equal(t, "email1@example.com,deliverable,accepted_email,1,false,false,false,false,,", lines[1])
+
+	c, _, stderr := newTestCLI(nil, sandbox)
+	assert.Equal(t, exitError, c.run(context.TODO(), []string{"--sandbox", "batch", "status", "1"}))
+	assert.Contains(t, stderr.String(), "checking batch:")
+
+	// a new sandbox has no jobs, submitted by another run
+	for _, command := range []string{"status", "wait", "download"} {
+		c, _, stderr = newTestCLI(nil, nil)
+		assert.Equal(t, exitError, c.run(context.TODO(), []string{"batch", command, "--sandbox", id}))
+		assert.Equal(t, "kickbox: sandbox batch jobs only exist while the submitting command runs, status, wait and download need the api\n", stderr.String())
+	}
+}
+
+func TestAPIKeySources(t *testing.T) {
+	svr := kickboxtest.NewServer(kickboxtest.WithAPIKey("secret"))
+	defer svr.Close()
+
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(config, []byte("api_key: secret\nbase_url: "+svr.URL+"\n"), 0o600))
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		code int
+	}{
+		{"flag", nil, []string{"--config", missing, "--base-url", svr.URL, "--api-key", "secret"}, exitError}, // explicit config not found
+		{"flag without config", map[string]string{"KICKBOX_CONFIG": ""}, []string{"--base-url", svr.URL, "--api-key", "secret"}, exitOK},
+		{"env", map[string]string{"KICKBOX_API_KEY": "secret"}, []string{"--base-url", svr.URL}, exitOK},
+		{"config", nil, []string{"--config", config}, exitOK},
+		{"config from env", map[string]string{"KICKBOX_CONFIG": config}, nil, exitOK},
+		{"flag over env", map[string]string{"KICKBOX_API_KEY": "wrong"}, []string{"--base-url", svr.URL, "--api-key", "secret"}, exitOK},
+		{"env over config", map[string]string{"KICKBOX_API_KEY": "wrong"}, []string{"--config", config}, exitError},
+	}
+	for _, test := range tests {
+		c, _, stderr := newTestCLI(test.env, nil)
+		code := c.run(context.TODO(), append(test.args, "verify", "deliverable@example.com"))
+		assert.Equal(t, test.code, code, test.name, stderr.String())
+	}
+}
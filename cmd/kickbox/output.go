@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/wakumaku/kickbox"
+)
+
+// output writes the command results in one of the supported formats.
+// A command writes a single kind of record, the headers are written before the first one.
+type output interface {
+	// verification writes the result of verifying an email
+	verification(resp *kickbox.ResponseVerify) error
+	// submitted writes the created batch job
+	submitted(resp *kickbox.ResponseVerifyBatch) error
+	// status writes the status of a batch job
+	status(resp *kickbox.VerifyBatchCheckResponse) error
+	// flush writes any buffered data
+	flush() error
+}
+
+var (
+	verificationColumns = []string{"email", "result", "reason", "sendex", "role", "free", "disposable", "accept_all", "did_you_mean", "message"}
+	submittedColumns    = []string{"id", "success", "message"}
+	statusColumns       = []string{"id", "status", "processed", "total", "deliverable", "undeliverable", "risky", "unknown", "error"}
+)
+
+func verificationRow(resp *kickbox.ResponseVerify) []string {
+	return []string{
+		resp.Email,
+		resp.Result.String(),
+		resp.Reason.String(),
+		strconv.FormatFloat(resp.Sendex, 'f', -1, 64),
+		strconv.FormatBool(resp.Role),
+		strconv.FormatBool(resp.Free),
+		strconv.FormatBool(resp.Disposable),
+		strconv.FormatBool(resp.AcceptAll),
+		resp.DidYouMean,
+		resp.Message,
+	}
+}
+
+func submittedRow(resp *kickbox.ResponseVerifyBatch) []string {
+	return []string{strconv.Itoa(resp.ID), strconv.FormatBool(resp.Success), resp.Message}
+}
+
+func statusRow(resp *kickbox.VerifyBatchCheckResponse) []string {
+	deliverable, undeliverable, risky, unknown := resp.Progress.Deliverable, resp.Progress.Undeliverable, resp.Progress.Risky, resp.Progress.Unknown
+	total := resp.Progress.Total
+	if resp.Status == kickbox.BatchCompleted {
+		deliverable, undeliverable, risky, unknown = resp.Stats.Deliverable, resp.Stats.Undeliverable, resp.Stats.Risky, resp.Stats.Unknown
+		total = resp.Stats.Addresses
+	}
+	processed := deliverable + undeliverable + risky + unknown
+
+	return []string{
+		strconv.Itoa(resp.ID),
+		resp.Status.String(),
+		strconv.Itoa(processed),
+		strconv.Itoa(total),
+		strconv.Itoa(deliverable),
+		strconv.Itoa(undeliverable),
+		strconv.Itoa(risky),
+		strconv.Itoa(unknown),
+		resp.Error,
+	}
+}
+
+// rowsOutput writes the records as rows, the columns header first
+type rowsOutput struct {
+	write       func(row []string) error
+	flushWriter func() error
+	header      func(columns []string) []string
+	headerDone  bool
+}
+
+func (o *rowsOutput) row(columns, row []string) error {
+	if !o.headerDone {
+		o.headerDone = true
+		if err := o.write(o.header(columns)); err != nil {
+			return err
+		}
+	}
+	return o.write(row)
+}
+
+func (o *rowsOutput) verification(resp *kickbox.ResponseVerify) error {
+	return o.row(verificationColumns, verificationRow(resp))
+}
+
+func (o *rowsOutput) submitted(resp *kickbox.ResponseVerifyBatch) error {
+	return o.row(submittedColumns, submittedRow(resp))
+}
+
+func (o *rowsOutput) status(resp *kickbox.VerifyBatchCheckResponse) error {
+	return o.row(statusColumns, statusRow(resp))
+}
+
+func (o *rowsOutput) flush() error {
+	return o.flushWriter()
+}
+
+// newCSVOutput writes CSV with a header row
+func newCSVOutput(w io.Writer) output {
+	cw := csv.NewWriter(w)
+	return &rowsOutput{
+		write: cw.Write,
+		flushWriter: func() error {
+			cw.Flush()
+			return cw.Error()
+		},
+		header: func(columns []string) []string { return columns },
+	}
+}
+
+// newTableOutput writes aligned columns with an uppercase header
+func newTableOutput(w io.Writer) output {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	return &rowsOutput{
+		write: func(row []string) error {
+			_, err := fmt.Fprintln(tw, strings.Join(row, "\t"))
+			return err
+		},
+		flushWriter: tw.Flush,
+		header: func(columns []string) []string {
+			header := make([]string, len(columns))
+			for i, column := range columns {
+				header[i] = strings.ToUpper(column)
+			}
+			return header
+		},
+	}
+}
+
+// jsonOutput writes a JSON document per line
+type jsonOutput struct {
+	enc *json.Encoder
+}
+
+// newJSONOutput writes the API responses as JSON lines
+func newJSONOutput(w io.Writer) output {
+	return &jsonOutput{enc: json.NewEncoder(w)}
+}
+
+func (o *jsonOutput) verification(resp *kickbox.ResponseVerify) error {
+	return o.enc.Encode(resp)
+}
+
+func (o *jsonOutput) submitted(resp *kickbox.ResponseVerifyBatch) error {
+	return o.enc.Encode(resp)
+}
+
+func (o *jsonOutput) status(resp *kickbox.VerifyBatchCheckResponse) error {
+	return o.enc.Encode(resp)
+}
+
+func (o *jsonOutput) flush() error {
+	return nil
+}
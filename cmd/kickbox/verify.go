@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/wakumaku/kickbox"
+)
+
+// verify verifies every email argument, the exit code reflects the worst result
+func (c *cli) verify(ctx context.Context, args []string) (int, error) {
+	fs := c.flagSet("kickbox verify", "[flags] <email>...")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return 0, err
+	}
+
+	out, err := c.output()
+	if err != nil {
+		return 0, err
+	}
+	cl, err := c.client()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+
+	code, failed := exitOK, false
+	for _, email := range fs.Args() {
+		_, resp, err := cl.Verify(ctx, email)
+		if err == nil && !resp.Success {
+			err = errors.New(resp.Message)
+		}
+		if err != nil {
+			fmt.Fprintf(c.stderr, "kickbox: verifying %s: %v\n", email, err)
+			failed = true
+			continue
+		}
+
+		if err := out.verification(resp); err != nil {
+			return 0, err
+		}
+		if rc := resultExitCode(resp.Result); rc > code {
+			code = rc
+		}
+	}
+	if err := out.flush(); err != nil {
+		return 0, err
+	}
+
+	if failed {
+		return exitError, nil
+	}
+	return code, nil
+}
+
+// resultExitCode maps the verification result to the exit code
+func resultExitCode(result kickbox.Result) int {
+	switch result {
+	case kickbox.ResultDeliverable:
+		return exitOK
+	case kickbox.ResultRisky:
+		return exitRisky
+	case kickbox.ResultUndeliverable:
+		return exitUndeliverable
+	}
+	return exitUnknown
+}
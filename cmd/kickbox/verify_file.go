@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+)
+
+// File formats of verify-file
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+// resultColumns are appended to the CSV columns of the input, the kickbox_ prefix avoids clashes
+var resultColumns = []string{
+	"kickbox_result", "kickbox_reason", "kickbox_sendex", "kickbox_role", "kickbox_free",
+	"kickbox_disposable", "kickbox_accept_all", "kickbox_did_you_mean", "kickbox_error",
+}
+
+// progressInterval is the minimum time between two progress lines
+const progressInterval = time.Second
+
+// errMissingEmail is reported in the output of the rows without an email
+var errMissingEmail = errors.New("missing email")
+
+// record is a row of the input file
+type record struct {
+	email  string
+	header []string                   // CSV header
+	fields []string                   // CSV columns
+	object map[string]json.RawMessage // JSONL object
+}
+
+// verifyFile verifies the emails of a CSV or JSONL file through the single verification
+// endpoint. The results are kept in a checkpoint until the output is written, a failed or
+// interrupted run resumes from it. Duplicated emails are verified once.
+func (c *cli) verifyFile(ctx context.Context, args []string) (int, error) {
+	fs := c.flagSet("kickbox verify-file", "[flags] --out <results.jsonl|results.csv> <input.csv|input.jsonl>")
+	column := fs.String("column", "email", "CSV column header or JSONL field holding the email")
+	out := fs.String("out", "", "output file, JSONL when its extension is .jsonl, .ndjson or .json, CSV otherwise")
+	checkpointPath := fs.String("checkpoint", "", "checkpoint file, Default: <out>.checkpoint")
+	workers := fs.Int("workers", 0, "concurrent verifications, Default: the client maximum connections")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return 0, err
+	}
+	if *out == "" {
+		fmt.Fprintln(c.stderr, "kickbox: --out is required")
+		fs.Usage()
+		return 0, errUsage
+	}
+	if *checkpointPath == "" {
+		*checkpointPath = *out + ".checkpoint"
+	}
+
+	input := fs.Arg(0)
+	inFormat, outFormat := fileFormat(input), fileFormat(*out)
+	if inFormat == formatJSONL && outFormat == formatCSV {
+		return 0, errors.New("CSV output needs a CSV input")
+	}
+
+	cl, err := c.client()
+	if err != nil {
+		return 0, err
+	}
+
+	cp, err := openCheckpoint(*checkpointPath)
+	if err != nil {
+		return 0, err
+	}
+	defer cp.Close()
+
+	// the emails not in the checkpoint, once each
+	var pending []string
+	rows, resumed := 0, 0
+	queued := map[string]bool{}
+	err = readInput(input, inFormat, *column, nil, func(r record) error {
+		rows++
+		if r.email == "" {
+			return nil
+		}
+		key := kickbox.EmailKey(r.email)
+		if queued[key] {
+			return nil
+		}
+		queued[key] = true
+		if _, done := cp.result(key); done {
+			resumed++
+			return nil
+		}
+		pending = append(pending, r.email)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+
+	failures, err := c.verifyPending(ctx, cl, cp, pending, *workers)
+	if err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("verification interrupted, run it again to resume: %w", err)
+	}
+
+	if err := writeResults(input, inFormat, *column, *out, outFormat, cp, failures); err != nil {
+		return 0, err
+	}
+
+	fmt.Fprintf(c.stderr, "kickbox: %d rows, %d emails verified, %d from checkpoint, %d failed\n",
+		rows, len(pending)-len(failures), resumed, len(failures))
+
+	if len(failures) > 0 {
+		fmt.Fprintf(c.stderr, "kickbox: run it again to retry the failed emails, checkpoint kept in %s\n", *checkpointPath)
+		return exitError, nil
+	}
+	if err := cp.remove(); err != nil {
+		return 0, fmt.Errorf("removing checkpoint: %v", err)
+	}
+	return exitOK, nil
+}
+
+// verifyPending verifies the emails, storing the results in the checkpoint.
+// It returns the errors by email key.
+func (c *cli) verifyPending(ctx context.Context, cl client, cp *checkpoint, emails []string, workers int) (map[string]error, error) {
+	var mu sync.Mutex
+	var last time.Time
+	progress := func(p kickbox.VerifyManyProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p.Completed < p.Total && time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		fmt.Fprintf(c.stderr, "kickbox: verified %d/%d, %d failed\n", p.Completed, p.Total, p.Failed)
+	}
+
+	failures := map[string]error{}
+	if len(emails) == 0 {
+		return failures, nil
+	}
+
+	// cancels the verifications left when the checkpoint can not be written
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var cpErr error
+	for r := range cl.VerifyMany(ctx, emails, kickbox.Workers(workers), kickbox.OnProgress(progress)) {
+		key := kickbox.EmailKey(r.Email)
+		err := r.Err
+		if err == nil && !r.Response.Success {
+			err = errors.New(r.Response.Message)
+		}
+		switch {
+		case err != nil:
+			failures[key] = err
+		case cpErr == nil:
+			if cpErr = cp.add(key, r.Response); cpErr != nil {
+				cancel()
+			}
+		}
+	}
+	return failures, cpErr
+}
+
+// writeResults writes every row of the input with its result appended
+func writeResults(input, inFormat, column, out, outFormat string, cp *checkpoint, failures map[string]error) error {
+	file, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("creating output: %v", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	csvw := csv.NewWriter(w)
+
+	onHeader := func(header []string) error {
+		if outFormat != formatCSV {
+			return nil
+		}
+		return csvw.Write(append(append([]string{}, header...), resultColumns...))
+	}
+	err = readInput(input, inFormat, column, onHeader, func(r record) error {
+		resp, err := rowResult(r, cp, failures)
+		if outFormat == formatJSONL {
+			return enc.Encode(jsonResult(r, resp, err))
+		}
+		fields := r.fields
+		for len(fields) < len(r.header) {
+			fields = append(fields, "")
+		}
+		return csvw.Write(append(fields, csvResult(resp, err)...))
+	})
+	if err != nil {
+		return err
+	}
+
+	csvw.Flush()
+	if err := csvw.Error(); err != nil {
+		return fmt.Errorf("writing output: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing output: %v", err)
+	}
+	return file.Close()
+}
+
+// rowResult returns the verification of the row email
+func rowResult(r record, cp *checkpoint, failures map[string]error) (*kickbox.ResponseVerify, error) {
+	if r.email == "" {
+		return nil, errMissingEmail
+	}
+	key := kickbox.EmailKey(r.email)
+	if err, failed := failures[key]; failed {
+		return nil, err
+	}
+	if resp, ok := cp.result(key); ok {
+		return resp, nil
+	}
+	return nil, errors.New("not verified")
+}
+
+// jsonResult is the input object with the response under "kickbox", or the error under "kickbox_error"
+func jsonResult(r record, resp *kickbox.ResponseVerify, err error) map[string]interface{} {
+	result := make(map[string]interface{}, len(r.object)+1)
+	for k, v := range r.object {
+		result[k] = v
+	}
+	for i, name := range r.header {
+		if i < len(r.fields) {
+			result[name] = r.fields[i]
+		}
+	}
+	if err != nil {
+		result["kickbox_error"] = err.Error()
+	} else {
+		result["kickbox"] = resp
+	}
+	return result
+}
+
+// csvResult returns the values of the resultColumns
+func csvResult(resp *kickbox.ResponseVerify, err error) []string {
+	if err != nil {
+		return []string{"", "", "", "", "", "", "", "", err.Error()}
+	}
+	return []string{
+		resp.Result.String(),
+		resp.Reason.String(),
+		strconv.FormatFloat(resp.Sendex, 'f', -1, 64),
+		strconv.FormatBool(resp.Role),
+		strconv.FormatBool(resp.Free),
+		strconv.FormatBool(resp.Disposable),
+		strconv.FormatBool(resp.AcceptAll),
+		resp.DidYouMean,
+		"",
+	}
+}
+
+// fileFormat guesses the format of a file by its extension
+func fileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson", ".json":
+		return formatJSONL
+	}
+	return formatCSV
+}
+
+// readInput calls fn with every record of the input file, and onHeader, if not nil,
+// with the CSV header. The email is read from the column with the given header,
+// or the field with the given name on JSONL files.
+func readInput(path, format, column string, onHeader func([]string) error, fn func(record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening input: %v", err)
+	}
+	defer file.Close()
+
+	if format == formatJSONL {
+		return readJSONL(file, column, fn)
+	}
+	return readCSV(file, column, onHeader, fn)
+}
+
+func readCSV(r io.Reader, column string, onHeader func([]string) error, fn func(record) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return errors.New("reading input: empty file")
+	}
+	if err != nil {
+		return fmt.Errorf("reading input: %v", err)
+	}
+	index := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("reading input: column %q not found", column)
+	}
+	if onHeader != nil {
+		if err := onHeader(header); err != nil {
+			return err
+		}
+	}
+
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading input: %v", err)
+		}
+
+		r := record{header: header, fields: fields}
+		if index < len(fields) {
+			r.email = strings.TrimSpace(fields[index])
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+}
+
+func readJSONL(r io.Reader, field string, fn func(record) error) error {
+	reader := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading input: %v", err)
+		}
+		if trimmed := strings.TrimSpace(string(line)); trimmed != "" {
+			rec := record{}
+			if err := json.Unmarshal([]byte(trimmed), &rec.object); err != nil {
+				return fmt.Errorf("reading input: line %d: %v", n, err)
+			}
+			var email string
+			if raw, ok := rec.object[field]; ok && json.Unmarshal(raw, &email) == nil {
+				rec.email = strings.TrimSpace(email)
+			}
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
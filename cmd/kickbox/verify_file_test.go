@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const verifyFileInput = `id,name,Email
+1,Bill,deliverable@example.com
+2,Joanna,role@example.com
+3,Milton,
+4,Bill again,Deliverable@Example.com
+5,Peter,undeliverable@example.com
+`
+
+func TestVerifyFileCSV(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.csv")
+	out := filepath.Join(dir, "results.csv")
+	assert.Nil(t, os.WriteFile(input, []byte(verifyFileInput), 0o644))
+
+	var calls int32
+	sandbox := kickbox.NewSandbox(kickbox.PrependSandboxRules(kickbox.SandboxRule{
+		Match: func(string) bool { atomic.AddInt32(&calls, 1); return false },
+	}))
+
+	c, _, stderr := newTestCLI(nil, sandbox)
+	code := c.run(context.TODO(), []string{"--sandbox", "verify-file", "--out", out, input})
+	assert.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls), "duplicates are verified once")
+	assert.Contains(t, stderr.String(), "kickbox: verified 3/3, 0 failed\n")
+	assert.Contains(t, stderr.String(), "kickbox: 5 rows, 3 emails verified, 0 from checkpoint, 0 failed\n")
+
+	results, err := os.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Equal(t, `id,name,Email,kickbox_result,kickbox_reason,kickbox_sendex,kickbox_role,kickbox_free,kickbox_disposable,kickbox_accept_all,kickbox_did_you_mean,kickbox_error
+1,Bill,deliverable@example.com,deliverable,accepted_email,1,false,false,false,false,,
+2,Joanna,role@example.com,risky,low_quality,0.7,true,false,false,false,,
+3,Milton,,,,,,,,,,missing email
+4,Bill again,Deliverable@Example.com,deliverable,accepted_email,1,false,false,false,false,,
+5,Peter,undeliverable@example.com,undeliverable,rejected_email,0,false,false,false,false,,
+`, string(results))
+
+	// the checkpoint is removed once complete
+	_, err = os.Stat(out + ".checkpoint")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestVerifyFileResume(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.csv")
+	out := filepath.Join(dir, "results.jsonl")
+	assert.Nil(t, os.WriteFile(input, []byte(verifyFileInput), 0o644))
+
+	var calls, down int32 = 0, 1
+	sandbox := kickbox.NewSandbox(kickbox.PrependSandboxRules(
+		kickbox.SandboxRule{
+			Match: func(string) bool { atomic.AddInt32(&calls, 1); return false },
+		},
+		kickbox.SandboxRule{
+			Match: func(email string) bool { return atomic.LoadInt32(&down) == 1 && strings.HasPrefix(email, "role@") },
+			Err:   &kickbox.APIError{HTTPStatus: http.StatusServiceUnavailable},
+		},
+	))
+
+	// the failed email is reported and kept for the next run
+	c, _, stderr := newTestCLI(nil, sandbox)
+	assert.Equal(t, exitError, c.run(context.TODO(), []string{"--sandbox", "verify-file", "--out", out, input}))
+	assert.Contains(t, stderr.String(), "kickbox: 5 rows, 2 emails verified, 0 from checkpoint, 1 failed\n")
+	assert.Contains(t, stderr.String(), "checkpoint kept in "+out+".checkpoint")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	lines := readJSONLines(t, out)
+	assert.Len(t, lines, 5)
+	assert.Equal(t, "kickbox api error: status 503", lines[1]["kickbox_error"])
+
+	// only the failed email is verified again
+	atomic.StoreInt32(&down, 0)
+	c, _, stderr = newTestCLI(nil, sandbox)
+	assert.Equal(t, exitOK, c.run(context.TODO(), []string{"--sandbox", "verify-file", "--out", out, input}))
+	assert.Contains(t, stderr.String(), "kickbox: 5 rows, 1 emails verified, 2 from checkpoint, 0 failed\n")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+
+	lines = readJSONLines(t, out)
+	assert.Len(t, lines, 5)
+	assert.Equal(t, "2", lines[1]["id"])
+	assert.Equal(t, "Joanna", lines[1]["name"])
+	assert.Equal(t, "risky", lines[1]["kickbox"].(map[string]interface{})["result"])
+	assert.Equal(t, "missing email", lines[2]["kickbox_error"])
+}
+
+func TestVerifyFileCheckpointTruncatedLine(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.jsonl")
+	out := filepath.Join(dir, "results.jsonl")
+	assert.Nil(t, os.WriteFile(input, []byte(`{"user":1,"mail":"deliverable@example.com"}
+{"user":2,"mail":"undeliverable@example.com"}
+`), 0o644))
+	assert.Nil(t, os.WriteFile(out+".checkpoint", []byte(`{"email":"deliverable@example.com","response":{"result":"deliverable","success":true}}
+{"email":"undeliverable@exa`), 0o644))
+
+	c, _, stderr := newTestCLI(nil, nil)
+	assert.Equal(t, exitOK, c.run(context.TODO(), []string{"--sandbox", "verify-file", "--column", "mail", "--out", out, input}))
+	assert.Contains(t, stderr.String(), "kickbox: 2 rows, 1 emails verified, 1 from checkpoint, 0 failed\n")
+
+	lines := readJSONLines(t, out)
+	assert.Equal(t, float64(1), lines[0]["user"])
+	assert.Equal(t, "deliverable", lines[0]["kickbox"].(map[string]interface{})["result"])
+	assert.Equal(t, "undeliverable", lines[1]["kickbox"].(map[string]interface{})["result"])
+}
+
+func TestVerifyFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.csv")
+	assert.Nil(t, os.WriteFile(input, []byte(verifyFileInput), 0o644))
+
+	c, _, stderr := newTestCLI(nil, nil)
+	assert.Equal(t, exitUsage, c.run(context.TODO(), []string{"--sandbox", "verify-file", input}))
+	assert.Contains(t, stderr.String(), "--out is required")
+
+	c, _, stderr = newTestCLI(nil, nil)
+	assert.Equal(t, exitError, c.run(context.TODO(), []string{"--sandbox", "verify-file", "--column", "mail", "--out", filepath.Join(dir, "out.csv"), input}))
+	assert.Contains(t, stderr.String(), `column "mail" not found`)
+
+	c, _, stderr = newTestCLI(nil, nil)
+	assert.Equal(t, exitError, c.run(context.TODO(), []string{"--sandbox", "verify-file", "--out", filepath.Join(dir, "out.csv"), filepath.Join(dir, "input.jsonl")}))
+	assert.Contains(t, stderr.String(), "CSV output needs a CSV input")
+
+	checkpoint := filepath.Join(dir, "corrupt.checkpoint")
+	assert.Nil(t, os.WriteFile(checkpoint, []byte("{}\n{}\n"), 0o644))
+	c, _, stderr = newTestCLI(nil, nil)
+	assert.Equal(t, exitError, c.run(context.TODO(), []string{"--sandbox", "verify-file", "--checkpoint", checkpoint, "--out", filepath.Join(dir, "out.csv"), input}))
+	assert.Contains(t, stderr.String(), "line 1: missing response")
+}
+
+func readJSONLines(t *testing.T, path string) []map[string]interface{} {
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var object map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &object))
+		lines = append(lines, object)
+	}
+	return lines
+}
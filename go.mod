@@ -4,11 +4,13 @@ go 1.17
 
 require (
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/net v0.17.0
 	golang.org/x/time v0.0.0-20211116232009-f0f3c7e86c11
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+	golang.org/x/text v0.13.0 // indirect
 )
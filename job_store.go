@@ -0,0 +1,196 @@
+package kickbox
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// compaction starts when the job store file has more stale records than this and than jobs
+const fileJobStoreCompactMinStale = 100
+
+// ErrJobStoreClosed is returned when writing to a closed job store
+var ErrJobStoreClosed = errors.New("job store is closed")
+
+// JobSubmitting is the status of a submission intent, recorded before uploading the
+// file. It is not a kickbox status: the job has no ID until the upload succeeds.
+const JobSubmitting BatchStatus = "submitting"
+
+// TrackedJob is a batch job recorded by a JobTracker. A job with ID 0 is a
+// submission intent, see JobSubmitting.
+type TrackedJob struct {
+	ID          int         `json:"id"`
+	Filename    string      `json:"filename,omitempty"`
+	Callback    string      `json:"callback,omitempty"`
+	Checksum    string      `json:"checksum"` // SHA-256 of the uploaded file, hex encoded
+	SubmittedAt time.Time   `json:"submitted_at"`
+	Status      BatchStatus `json:"status"`               // last known status
+	CheckedAt   time.Time   `json:"checked_at,omitempty"` // time of the last status check
+	Error       string      `json:"error,omitempty"`      // error of a failed job
+}
+
+// JobStore persists the batch jobs of a JobTracker and their submission intents,
+// the jobs with ID 0, which are identified by their checksum instead
+type JobStore interface {
+	// Save inserts the job or replaces the one with the same ID. An intent replaces the
+	// one with the same checksum, and is removed by saving a job with that checksum
+	// submitted at or after it.
+	Save(job TrackedJob) error
+	// Get returns the job with the id
+	Get(id int) (*TrackedJob, bool, error)
+	// FindByChecksum returns the last submitted job or intent with the checksum
+	FindByChecksum(checksum string) (*TrackedJob, bool, error)
+	// List returns every job and intent, sorted by submission time
+	List() ([]TrackedJob, error)
+}
+
+// FileJobStore is a JobStore persisted in a local file. The jobs are held in memory
+// and every change is appended to the file, which is compacted once most of its records are stale.
+type FileJobStore struct {
+	mu      sync.Mutex
+	log     *appendLog
+	jobs    map[int]TrackedJob
+	intents map[string]TrackedJob // by checksum
+}
+
+// Ensure JobStore implementation
+var _ JobStore = (*FileJobStore)(nil)
+
+// NewFileJobStore opens the job store at path, creating it if it does not exist.
+// Close must be called to release the file.
+func NewFileJobStore(path string) (*FileJobStore, error) {
+	s := &FileJobStore{
+		log:     &appendLog{path: path, name: "job store file", errClosed: ErrJobStoreClosed, sync: true},
+		jobs:    map[int]TrackedJob{},
+		intents: map[string]TrackedJob{},
+	}
+
+	err := s.log.open(func(data []byte) error {
+		var job TrackedJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			return err
+		}
+		s.put(job)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Save inserts the job or replaces the one with the same ID, or the intent with the
+// same checksum. The file is synced to disk. A failed compaction does not fail the
+// save, the job being recorded: it is logged and retried on the next save.
+func (s *FileJobStore) Save(job TrackedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.log.append(job); err != nil {
+		return err
+	}
+	s.put(job)
+
+	if !s.log.shouldCompact(len(s.jobs)+len(s.intents), fileJobStoreCompactMinStale) {
+		return nil
+	}
+	if err := s.compact(); err != nil {
+		log.Printf("kickbox: %v", err)
+	}
+	return nil
+}
+
+// Get returns the job with the id
+func (s *FileJobStore) Get(id int) (*TrackedJob, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, found := s.jobs[id]
+	if !found {
+		return nil, false, nil
+	}
+	return &job, true, nil
+}
+
+// FindByChecksum returns the last submitted job or intent with the checksum
+func (s *FileJobStore) FindByChecksum(checksum string) (*TrackedJob, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var last *TrackedJob
+	if intent, found := s.intents[checksum]; found {
+		last = &intent
+	}
+	for _, job := range s.jobs {
+		if job.Checksum != checksum {
+			continue
+		}
+		if last == nil || job.SubmittedAt.After(last.SubmittedAt) {
+			job := job
+			last = &job
+		}
+	}
+	return last, last != nil, nil
+}
+
+// List returns every job and intent, sorted by submission time
+func (s *FileJobStore) List() ([]TrackedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(), nil
+}
+
+// Close flushes the file to disk and releases it
+func (s *FileJobStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.log.close()
+}
+
+// put inserts the job or intent in memory. Must be called with the lock held.
+func (s *FileJobStore) put(job TrackedJob) {
+	if job.ID == 0 {
+		s.intents[job.Checksum] = job
+		return
+	}
+	if intent, found := s.intents[job.Checksum]; found && !job.SubmittedAt.Before(intent.SubmittedAt) {
+		delete(s.intents, job.Checksum)
+	}
+	s.jobs[job.ID] = job
+}
+
+// list returns the jobs and intents sorted by submission time. Must be called with the lock held.
+func (s *FileJobStore) list() []TrackedJob {
+	jobs := make([]TrackedJob, 0, len(s.jobs)+len(s.intents))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	for _, intent := range s.intents {
+		jobs = append(jobs, intent)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].SubmittedAt.Equal(jobs[j].SubmittedAt) {
+			return jobs[i].ID < jobs[j].ID
+		}
+		return jobs[i].SubmittedAt.Before(jobs[j].SubmittedAt)
+	})
+	return jobs
+}
+
+// compact rewrites the file with the jobs. Must be called with the lock held.
+func (s *FileJobStore) compact() error {
+	return s.log.compact(func(write func(record interface{}) error) error {
+		for _, job := range s.list() {
+			if err := write(job); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
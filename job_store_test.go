@@ -0,0 +1,171 @@
+package kickbox_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileJobStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	store, err := kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+
+	submitted := time.Date(2021, 11, 20, 10, 0, 0, 0, time.UTC)
+	first := kickbox.TrackedJob{ID: 1, Filename: "a", Checksum: "abc", SubmittedAt: submitted, Status: kickbox.BatchFailed, Error: "bad file"}
+	second := kickbox.TrackedJob{ID: 2, Callback: "https://example.com", Checksum: "abc", SubmittedAt: submitted.Add(time.Hour), Status: kickbox.BatchStarting}
+	third := kickbox.TrackedJob{ID: 3, Checksum: "def", SubmittedAt: submitted.Add(-time.Hour), Status: kickbox.BatchStarting}
+	for _, job := range []kickbox.TrackedJob{first, second, third} {
+		assert.Nil(t, store.Save(job))
+	}
+	second.Status = kickbox.BatchProcessing
+	assert.Nil(t, store.Save(second))
+
+	job, found, err := store.Get(2)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, second, *job)
+
+	_, found, err = store.Get(4)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	// the last submitted one
+	job, found, err = store.FindByChecksum("abc")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 2, job.ID)
+
+	_, found, err = store.FindByChecksum("xyz")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	assert.Nil(t, store.Close())
+	assert.Equal(t, kickbox.ErrJobStoreClosed, store.Save(first))
+
+	// reopened, the truncated last line is dropped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"id":4,"checks`)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	store, err = kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	jobs, err := store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []kickbox.TrackedJob{third, first, second}, jobs)
+}
+
+func TestFileJobStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	store, err := kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	job := kickbox.TrackedJob{ID: 1, Checksum: "abc", Status: kickbox.BatchProcessing}
+	for i := 0; i < 150; i++ {
+		assert.Nil(t, store.Save(job))
+	}
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Less(t, bytes.Count(data, []byte("\n")), 101)
+
+	jobs, err := store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []kickbox.TrackedJob{job}, jobs)
+}
+
+func TestFileJobStoreCompactionFailure(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	store, err := kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	// the temporary file cannot be created
+	assert.Nil(t, os.Mkdir(path+".tmp", 0o700))
+
+	job := kickbox.TrackedJob{ID: 1, Checksum: "abc", Status: kickbox.BatchProcessing}
+	for i := 0; i < 150; i++ {
+		assert.Nil(t, store.Save(job), "the job is saved anyway")
+	}
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 150, bytes.Count(data, []byte("\n")))
+	assert.Contains(t, logs.String(), "kickbox: compacting job store file:")
+
+	// retried on the next save
+	assert.Nil(t, os.Remove(path+".tmp"))
+	job.Status = kickbox.BatchCompleted
+	assert.Nil(t, store.Save(job))
+	data, err = os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, bytes.Count(data, []byte("\n")))
+
+	assert.Nil(t, store.Save(job))
+	jobs, err := store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []kickbox.TrackedJob{job}, jobs)
+}
+
+func TestFileJobStoreCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	assert.Nil(t, os.WriteFile(path, []byte("{\"id\":1}\nnot json\n{\"id\":2}\n"), 0o600))
+
+	_, err := kickbox.NewFileJobStore(path)
+	assert.Contains(t, err.Error(), "line 2:")
+}
+
+func TestFileJobStoreIntents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	store, err := kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+
+	submitted := time.Date(2021, 11, 20, 10, 0, 0, 0, time.UTC)
+	earlier := kickbox.TrackedJob{ID: 1, Checksum: "abc", SubmittedAt: submitted.Add(-time.Hour), Status: kickbox.BatchFailed}
+	intent := kickbox.TrackedJob{Filename: "a", Checksum: "abc", SubmittedAt: submitted, Status: kickbox.JobSubmitting}
+	assert.Nil(t, store.Save(intent))
+	// a job submitted before does not resolve the intent
+	assert.Nil(t, store.Save(earlier))
+
+	job, found, err := store.FindByChecksum("abc")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, intent, *job)
+
+	_, found, err = store.Get(0)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	// kept after a restart
+	assert.Nil(t, store.Close())
+	store, err = kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	jobs, err := store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []kickbox.TrackedJob{earlier, intent}, jobs)
+
+	// the job of the submission replaces the intent
+	created := kickbox.TrackedJob{ID: 2, Filename: "a", Checksum: "abc", SubmittedAt: submitted.Add(time.Minute), Status: kickbox.BatchStarting}
+	assert.Nil(t, store.Save(created))
+
+	jobs, err = store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []kickbox.TrackedJob{earlier, created}, jobs)
+}
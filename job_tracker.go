@@ -0,0 +1,342 @@
+package kickbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrSubmissionInterrupted is matched by errors.Is when a submission was interrupted
+// before the job ID was recorded: the file may have been uploaded, and paid for, or not.
+// The submission is kept unresolved until JobTracker.Resolve is called.
+var ErrSubmissionInterrupted = errors.New("batch submission interrupted")
+
+// JobTracker records the submitted batch jobs in a JobStore, so they survive a restart
+// of the process: unfinished jobs are polled again with Resume and the same input is
+// not submitted, and paid for, twice.
+type JobTracker struct {
+	verifier Verifier
+	store    JobStore
+
+	mu       sync.Mutex
+	inflight map[string]chan struct{} // checksums being submitted, closed when done
+}
+
+// NewJobTracker creates a tracker submitting and checking the jobs with the verifier
+func NewJobTracker(verifier Verifier, store JobStore) *JobTracker {
+	return &JobTracker{verifier: verifier, store: store, inflight: map[string]chan struct{}{}}
+}
+
+// Submit submits the file as a batch job and records it. When a job with the same
+// file checksum was already submitted, and has not failed, that job is returned
+// instead with existing set to true. Concurrent submissions of the same file wait
+// for each other.
+//
+// A submission intent is recorded before uploading the file. When the upload fails
+// without a definite answer of the server, e.g. a timeout or a crash, the intent is
+// kept and the next submissions of the file fail with ErrSubmissionInterrupted until
+// Resolve is called.
+// Files not implementing io.Seeker are spooled to a temporary file to compute the checksum.
+func (t *JobTracker) Submit(ctx context.Context, file io.ReadCloser, opts ...VerifyBatchOption) (job *TrackedJob, existing bool, err error) {
+	defer file.Close()
+
+	upload, checksum, err := checksumFile(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("computing checksum: %v", err)
+	}
+	defer upload.Close()
+
+	unlock, err := t.lock(ctx, checksum)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	job, found, err := t.store.FindByChecksum(checksum)
+	if err != nil {
+		return nil, false, fmt.Errorf("finding job: %w", err)
+	}
+	switch {
+	case found && job.Status == JobSubmitting:
+		return nil, false, interruptedError(job)
+	case found && job.Status != BatchFailed:
+		return job, true, nil
+	}
+
+	options := VerifyBatchRequestOptions{}
+	for _, apply := range opts {
+		apply(&options)
+	}
+
+	intent := TrackedJob{
+		Filename:    options.filename,
+		Callback:    options.callback,
+		Checksum:    checksum,
+		SubmittedAt: time.Now().UTC(),
+		Status:      JobSubmitting,
+	}
+	if err := t.store.Save(intent); err != nil {
+		return nil, false, fmt.Errorf("saving submission intent: %w", err)
+	}
+
+	resp, err := t.verifier.VerifyBatch(ctx, upload, opts...)
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("batch not created: %s", resp.Message)
+	}
+	if err != nil {
+		if uncertainSubmission(err) {
+			return nil, false, err
+		}
+		intent.Status = BatchFailed
+		intent.Error = err.Error()
+		if serr := t.store.Save(intent); serr != nil {
+			return nil, false, fmt.Errorf("%v, saving submission intent: %w", err, serr)
+		}
+		return nil, false, err
+	}
+
+	job = &TrackedJob{
+		ID:          resp.ID,
+		Filename:    options.filename,
+		Callback:    options.callback,
+		Checksum:    checksum,
+		SubmittedAt: time.Now().UTC(),
+		Status:      BatchStarting,
+	}
+	if err := t.store.Save(*job); err != nil {
+		return job, false, fmt.Errorf("saving batch %d: %w", job.ID, err)
+	}
+	return job, false, nil
+}
+
+// Resolve settles the interrupted submission of the file with the checksum, once the
+// batch jobs of the account have been looked up: id is the job created by the upload,
+// which is tracked from now on, or 0 when none was created and the file can be submitted again.
+func (t *JobTracker) Resolve(ctx context.Context, checksum string, id int) (*TrackedJob, error) {
+	unlock, err := t.lock(ctx, checksum)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	job, found, err := t.store.FindByChecksum(checksum)
+	if err != nil {
+		return nil, fmt.Errorf("finding job: %w", err)
+	}
+	if !found || job.Status != JobSubmitting {
+		return nil, fmt.Errorf("no interrupted submission of checksum %s", checksum)
+	}
+
+	if id == 0 {
+		job.Status = BatchFailed
+		job.Error = "submission interrupted, no batch created"
+	} else {
+		job.ID = id
+		job.Status = BatchStarting
+	}
+	if err := t.store.Save(*job); err != nil {
+		return nil, fmt.Errorf("saving submission of checksum %s: %w", checksum, err)
+	}
+	return job, nil
+}
+
+// Check checks the status of the tracked job, recording it
+func (t *JobTracker) Check(ctx context.Context, id int) (*VerifyBatchCheckResponse, error) {
+	job, found, err := t.store.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("getting batch %d: %w", id, err)
+	}
+	if !found {
+		return nil, fmt.Errorf("batch %d is not tracked", id)
+	}
+	return t.check(ctx, job)
+}
+
+// Wait polls the tracked job until it is completed, recording every status, see WaitForBatch
+func (t *JobTracker) Wait(ctx context.Context, id int, opts ...WaitForBatchOption) (*VerifyBatchCheckResponse, error) {
+	check := func(ctx context.Context, batchID string) (*VerifyBatchCheckResponse, error) {
+		return t.Check(ctx, id)
+	}
+	return waitForBatch(ctx, check, strconv.Itoa(id), opts)
+}
+
+// Resume checks the status of every job neither completed nor failed, e.g. after a restart.
+// It returns the jobs checked with their new status, the jobs failing to be checked keep
+// their last known status and the first error is returned. The interrupted submissions
+// are returned too, with an error matching ErrSubmissionInterrupted, see Resolve.
+func (t *JobTracker) Resume(ctx context.Context) ([]TrackedJob, error) {
+	jobs, err := t.store.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+
+	var resumed []TrackedJob
+	var firstErr error
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Status.IsTerminal() {
+			continue
+		}
+		if job.Status == JobSubmitting {
+			if !t.submitting(job.Checksum) && firstErr == nil {
+				firstErr = interruptedError(job)
+			}
+			resumed = append(resumed, *job)
+			continue
+		}
+		if _, err := t.check(ctx, job); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		resumed = append(resumed, *job)
+	}
+	return resumed, firstErr
+}
+
+// Jobs returns every tracked job and submission intent, sorted by submission time
+func (t *JobTracker) Jobs() ([]TrackedJob, error) {
+	return t.store.List()
+}
+
+// lock waits until no other submission of the checksum is in progress and holds it
+// until the returned function is called
+func (t *JobTracker) lock(ctx context.Context, checksum string) (func(), error) {
+	for {
+		t.mu.Lock()
+		done, busy := t.inflight[checksum]
+		if !busy {
+			done = make(chan struct{})
+			t.inflight[checksum] = done
+			t.mu.Unlock()
+			return func() {
+				t.mu.Lock()
+				delete(t.inflight, checksum)
+				t.mu.Unlock()
+				close(done)
+			}, nil
+		}
+		t.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// submitting reports whether the checksum is being submitted by this tracker
+func (t *JobTracker) submitting(checksum string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, busy := t.inflight[checksum]
+	return busy
+}
+
+// interruptedError reports the unresolved intent
+func interruptedError(intent *TrackedJob) error {
+	return fmt.Errorf("%w: checksum %s submitted at %s, see JobTracker.Resolve",
+		ErrSubmissionInterrupted, intent.Checksum, intent.SubmittedAt.Format(time.RFC3339))
+}
+
+// uncertainSubmission reports whether the batch may have been created despite the error:
+// the upload may have reached the server without its response coming back
+func uncertainSubmission(err error) bool {
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		for _, attempt := range retryErr.Attempts {
+			if uncertainSubmission(attempt) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatus >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// check checks the job status, updating and saving the job
+func (t *JobTracker) check(ctx context.Context, job *TrackedJob) (*VerifyBatchCheckResponse, error) {
+	resp, err := t.verifier.VerifyBatchCheck(ctx, strconv.Itoa(job.ID))
+	if err != nil {
+		return nil, fmt.Errorf("checking batch %d: %w", job.ID, err)
+	}
+
+	job.Status = resp.Status
+	job.Error = resp.Error
+	job.CheckedAt = time.Now().UTC()
+	if err := t.store.Save(*job); err != nil {
+		return resp, fmt.Errorf("saving batch %d: %w", job.ID, err)
+	}
+	return resp, nil
+}
+
+// checksumFile returns the SHA-256 of the file and a reader of its content, rewinded
+// or spooled to a temporary file removed on close
+func checksumFile(file io.Reader) (io.ReadCloser, string, error) {
+	hash := sha256.New()
+
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			if _, err := io.Copy(hash, seeker); err != nil {
+				return nil, "", err
+			}
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return nil, "", err
+			}
+			return &seekableNopCloser{seeker}, hex.EncodeToString(hash.Sum(nil)), nil
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "kickbox-batch-*.csv")
+	if err != nil {
+		return nil, "", err
+	}
+	spooled := &spooledFile{tmp}
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), file); err != nil {
+		spooled.Close()
+		return nil, "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, "", err
+	}
+	return spooled, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// seekableNopCloser keeps the file seekable for the upload retries, the caller closes it
+type seekableNopCloser struct {
+	io.ReadSeeker
+}
+
+func (seekableNopCloser) Close() error { return nil }
+
+// spooledFile is a temporary file removed on close, closing it twice is safe
+type spooledFile struct {
+	*os.File
+}
+
+func (f *spooledFile) Close() error {
+	err := f.File.Close()
+	if rerr := os.Remove(f.Name()); rerr != nil && !errors.Is(rerr, os.ErrNotExist) && err == nil {
+		err = rerr
+	}
+	if errors.Is(err, os.ErrClosed) {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,260 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobTracker(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+
+	sandbox := kickbox.NewSandbox(kickbox.SandboxBatchSteps(1))
+	store, err := kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+	tracker := kickbox.NewJobTracker(sandbox, store)
+
+	file, err := os.Open("./testdata/sample.csv")
+	assert.Nil(t, err)
+	job, existing, err := tracker.Submit(context.TODO(), file, kickbox.Filename("sample"), kickbox.Callback("https://example.com"))
+	assert.Nil(t, err)
+	assert.False(t, existing)
+	assert.Equal(t, 123456, job.ID)
+	assert.Equal(t, "sample", job.Filename)
+	assert.Equal(t, "https://example.com", job.Callback)
+	assert.Equal(t, kickbox.BatchStarting, job.Status)
+	assert.Len(t, job.Checksum, 64)
+
+	// the same content is not submitted again, even if not seekable
+	content, err := os.ReadFile("./testdata/sample.csv")
+	assert.Nil(t, err)
+	again, existing, err := tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader(string(content))))
+	assert.Nil(t, err)
+	assert.True(t, existing)
+	assert.Equal(t, job, again)
+
+	other, existing, err := tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+	assert.Nil(t, err)
+	assert.False(t, existing)
+	assert.Equal(t, 123457, other.ID)
+
+	// the spooled files are removed
+	files, err := os.ReadDir(tmp)
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+
+	check, err := tracker.Wait(context.TODO(), other.ID, kickbox.PollInterval(time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchCompleted, check.Status)
+
+	_, err = tracker.Check(context.TODO(), 1)
+	assert.EqualError(t, err, "batch 1 is not tracked")
+
+	// restart, only the unfinished job is checked
+	assert.Nil(t, store.Close())
+	store, err = kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	tracker = kickbox.NewJobTracker(sandbox, store)
+
+	resumed, err := tracker.Resume(context.TODO())
+	assert.Nil(t, err)
+	assert.Len(t, resumed, 1)
+	assert.Equal(t, job.ID, resumed[0].ID)
+	assert.Equal(t, kickbox.BatchStarting, resumed[0].Status)
+	assert.False(t, resumed[0].CheckedAt.IsZero())
+
+	resumed, err = tracker.Resume(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchProcessing, resumed[0].Status)
+
+	_, err = tracker.Wait(context.TODO(), job.ID, kickbox.PollInterval(time.Millisecond))
+	assert.Nil(t, err)
+
+	resumed, err = tracker.Resume(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, resumed)
+
+	jobs, err := tracker.Jobs()
+	assert.Nil(t, err)
+	assert.Len(t, jobs, 2)
+	for _, j := range jobs {
+		assert.Equal(t, kickbox.BatchCompleted, j.Status)
+	}
+}
+
+func TestJobTrackerResubmitsFailedJobs(t *testing.T) {
+	store, err := kickbox.NewFileJobStore(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	assert.Nil(t, err)
+	defer store.Close()
+	tracker := kickbox.NewJobTracker(kickbox.NewSandbox(), store)
+
+	job, _, err := tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+	assert.Nil(t, err)
+
+	job.Status = kickbox.BatchFailed
+	assert.Nil(t, store.Save(*job))
+
+	again, existing, err := tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+	assert.Nil(t, err)
+	assert.False(t, existing)
+	assert.NotEqual(t, job.ID, again.ID)
+	assert.Equal(t, job.Checksum, again.Checksum)
+
+	// an unknown job keeps its status, the error is reported
+	assert.Nil(t, store.Save(kickbox.TrackedJob{ID: 99, Checksum: "abc", Status: kickbox.BatchProcessing}))
+	resumed, err := tracker.Resume(context.TODO())
+	assert.EqualError(t, err, "checking batch 99: (sandbox) batch not found: 99")
+	assert.Len(t, resumed, 2)
+}
+
+func TestJobTrackerInterruptedSubmission(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	store, err := kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+	sandbox := kickbox.NewSandbox()
+	verifier := &batchVerifier{Verifier: sandbox}
+	tracker := kickbox.NewJobTracker(verifier, store)
+
+	// the upload may have created the job
+	verifier.err = &url.Error{Op: "Put", URL: "https://api.kickbox.com/v2/verify-batch", Err: io.ErrUnexpectedEOF}
+	_, _, err = tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	// not submitted again, even after a restart
+	verifier.err = nil
+	_, _, err = tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+	assert.True(t, errors.Is(err, kickbox.ErrSubmissionInterrupted))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&verifier.calls))
+
+	assert.Nil(t, store.Close())
+	store, err = kickbox.NewFileJobStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	tracker = kickbox.NewJobTracker(verifier, store)
+
+	resumed, err := tracker.Resume(context.TODO())
+	assert.True(t, errors.Is(err, kickbox.ErrSubmissionInterrupted))
+	assert.Len(t, resumed, 1)
+	assert.Equal(t, 0, resumed[0].ID)
+	assert.Equal(t, kickbox.JobSubmitting, resumed[0].Status)
+	checksum := resumed[0].Checksum
+
+	// no job was created, the file can be submitted again
+	failed, err := tracker.Resolve(context.TODO(), checksum, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchFailed, failed.Status)
+	_, err = tracker.Resolve(context.TODO(), checksum, 0)
+	assert.EqualError(t, err, "no interrupted submission of checksum "+checksum)
+
+	job, existing, err := tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+	assert.Nil(t, err)
+	assert.False(t, existing)
+	assert.Equal(t, 123456, job.ID)
+
+	jobs, err := tracker.Jobs()
+	assert.Nil(t, err)
+	assert.Equal(t, []kickbox.TrackedJob{*job}, jobs)
+
+	// the job created by the interrupted upload is tracked
+	verifier.err = &url.Error{Op: "Put", URL: "https://api.kickbox.com/v2/verify-batch", Err: context.DeadlineExceeded}
+	_, _, err = tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("risky@example.com\n")))
+	assert.NotNil(t, err)
+	created, err := sandbox.VerifyBatch(context.TODO(), io.NopCloser(strings.NewReader("risky@example.com\n")))
+	assert.Nil(t, err)
+
+	resumed, err = tracker.Resume(context.TODO())
+	assert.True(t, errors.Is(err, kickbox.ErrSubmissionInterrupted))
+	resolved, err := tracker.Resolve(context.TODO(), resumed[1].Checksum, created.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, created.ID, resolved.ID)
+	assert.Equal(t, kickbox.BatchStarting, resolved.Status)
+
+	_, err = tracker.Check(context.TODO(), created.ID)
+	assert.Nil(t, err)
+}
+
+func TestJobTrackerRejectedSubmission(t *testing.T) {
+	store, err := kickbox.NewFileJobStore(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	assert.Nil(t, err)
+	defer store.Close()
+	verifier := &batchVerifier{Verifier: kickbox.NewSandbox(), err: &kickbox.APIError{HTTPStatus: 403, Message: "insufficient balance"}}
+	tracker := kickbox.NewJobTracker(verifier, store)
+
+	// the server answered, no job was created
+	_, _, err = tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+	assert.True(t, errors.Is(err, kickbox.ErrInsufficientBalance))
+
+	jobs, err := tracker.Jobs()
+	assert.Nil(t, err)
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, kickbox.BatchFailed, jobs[0].Status)
+	assert.Equal(t, "kickbox api error: status 403: insufficient balance", jobs[0].Error)
+
+	verifier.err = nil
+	job, existing, err := tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+	assert.Nil(t, err)
+	assert.False(t, existing)
+	assert.Equal(t, 123456, job.ID)
+}
+
+func TestJobTrackerConcurrentSubmissions(t *testing.T) {
+	store, err := kickbox.NewFileJobStore(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	assert.Nil(t, err)
+	defer store.Close()
+	verifier := &batchVerifier{Verifier: kickbox.NewSandbox()}
+	tracker := kickbox.NewJobTracker(verifier, store)
+
+	var wg sync.WaitGroup
+	var created int32
+	ids := make([]int, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			job, existing, err := tracker.Submit(context.TODO(), io.NopCloser(strings.NewReader("deliverable@example.com\n")))
+			assert.Nil(t, err)
+			if !existing {
+				atomic.AddInt32(&created, 1)
+			}
+			ids[i] = job.ID
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), created)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&verifier.calls))
+	for _, id := range ids {
+		assert.Equal(t, 123456, id)
+	}
+}
+
+// batchVerifier counts the batch uploads, failing them with err if set
+type batchVerifier struct {
+	kickbox.Verifier
+	err   error
+	calls int32
+}
+
+func (v *batchVerifier) VerifyBatch(ctx context.Context, file io.ReadCloser, opts ...kickbox.VerifyBatchOption) (*kickbox.ResponseVerifyBatch, error) {
+	atomic.AddInt32(&v.calls, 1)
+	if v.err != nil {
+		file.Close()
+		return nil, v.err
+	}
+	return v.Verifier.VerifyBatch(ctx, file, opts...)
+}
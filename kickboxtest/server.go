@@ -0,0 +1,459 @@
+// Package kickboxtest provides an in-process fake of the kickbox HTTP API for integration tests.
+//
+//	svr := kickboxtest.NewServer()
+//	defer svr.Close()
+//
+//	client, _ := kickbox.New("apikey", kickbox.OverrideBaseURL(svr.URL))
+package kickboxtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+)
+
+// Fault describes an error the server injects in its responses
+type Fault struct {
+	Path       string        // path prefix the fault applies to, every path when empty
+	Latency    time.Duration // delay before responding
+	Status     int           // replies with this status code and a kickbox error body
+	RetryAfter string        // Retry-After header sent along with Status
+	Malformed  bool          // replies with a malformed JSON body
+	Times      int           // number of requests affected, 0 until cleared
+}
+
+// Server is a fake kickbox API following the sandbox address rules
+// see: https://docs.kickbox.com/docs/sandbox-api
+type Server struct {
+	*httptest.Server
+
+	sandbox *kickbox.ClientSandbox
+
+	mu             sync.Mutex
+	apiKey         string
+	balance        int
+	startingFor    time.Duration
+	processingFor  time.Duration
+	offset         time.Duration // simulated time elapsed
+	nextID         int
+	jobs           map[int]*job
+	faults         []*Fault
+	requests       map[string]int
+	callbackClient *http.Client
+}
+
+// job is a batch job
+type job struct {
+	id        int
+	name      string
+	callback  string
+	createdAt time.Time
+	results   kickbox.BatchReport
+	notified  bool
+}
+
+// Option configures the server
+type Option func(*Server)
+
+// WithAPIKey makes the server reject requests not authenticated with key.
+// By default any non empty key is accepted.
+func WithAPIKey(key string) Option {
+	return func(s *Server) {
+		s.apiKey = key
+	}
+}
+
+// WithBalance sets the initial verification credit balance. Default: 1000
+func WithBalance(n int) Option {
+	return func(s *Server) {
+		s.balance = n
+	}
+}
+
+// WithBatchTiming sets how long, in simulated time, a batch job stays
+// "starting" and "processing". Default: 1 and 10 seconds
+func WithBatchTiming(starting, processing time.Duration) Option {
+	return func(s *Server) {
+		s.startingFor = starting
+		s.processingFor = processing
+	}
+}
+
+// NewServer starts a fake kickbox API server, it must be closed when done
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		sandbox:        kickbox.NewSandbox(),
+		balance:        1000,
+		startingFor:    time.Second,
+		processingFor:  10 * time.Second,
+		nextID:         1,
+		jobs:           map[int]*job{},
+		requests:       map[string]int{},
+		callbackClient: &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, apply := range opts {
+		apply(s)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Advance moves the simulated time forward, making batch jobs progress.
+// Callbacks of the jobs completed are sent before returning.
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	s.offset += d
+	s.mu.Unlock()
+
+	s.notifyCompleted()
+}
+
+// Balance returns the remaining verification credit balance
+func (s *Server) Balance() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.balance
+}
+
+// Requests returns the number of requests received on path
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// InjectFault adds a fault, faults are applied in the order they were added
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all the faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// now returns the simulated time
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *Server) serveHTTP(rw http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	fault := s.fault(r.URL.Path)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			if fault.RetryAfter != "" {
+				rw.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			writeError(rw, fault.Status, http.StatusText(fault.Status))
+			return
+		}
+		if fault.Malformed {
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(http.StatusOK)
+			_, _ = rw.Write([]byte(`{"success":tru`))
+			return
+		}
+	}
+
+	const downloadPath = "/download/"
+	if strings.HasPrefix(r.URL.Path, downloadPath) {
+		s.download(rw, r, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, downloadPath), ".csv"))
+		return
+	}
+
+	if !s.authenticated(r) {
+		writeError(rw, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	const (
+		verifyPath      = "/v2/verify"
+		verifyBatchPath = "/v2/verify-batch"
+	)
+	switch {
+	case r.URL.Path == verifyPath && r.Method == http.MethodGet:
+		s.verify(rw, r, start)
+	case r.URL.Path == verifyBatchPath && r.Method == http.MethodPut:
+		s.verifyBatch(rw, r)
+	case strings.HasPrefix(r.URL.Path, verifyBatchPath+"/") && r.Method == http.MethodGet:
+		s.verifyBatchCheck(rw, strings.TrimPrefix(r.URL.Path, verifyBatchPath+"/"))
+	default:
+		writeError(rw, http.StatusNotFound, "Not found")
+	}
+}
+
+// fault returns the fault to apply on the path, if any. Must be called with the lock held.
+func (s *Server) fault(path string) *Fault {
+	for i, f := range s.faults {
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// authenticated checks the api key of the request, sent in the Authorization
+// header or in the apikey query param
+func (s *Server) authenticated(r *http.Request) bool {
+	key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if key == "" {
+		key = r.URL.Query().Get("apikey")
+	}
+	if key == "" {
+		return false
+	}
+	return s.apiKey == "" || key == s.apiKey
+}
+
+// charge takes n credits from the balance, returns false when it is insufficient
+func (s *Server) charge(n int) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.balance < n {
+		return s.balance, false
+	}
+	s.balance -= n
+	return s.balance, true
+}
+
+func (s *Server) verify(rw http.ResponseWriter, r *http.Request, start time.Time) {
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		writeError(rw, http.StatusBadRequest, "Missing email parameter")
+		return
+	}
+
+	_, resp, err := s.sandbox.Verify(r.Context(), email)
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !resp.Success {
+		writeError(rw, http.StatusForbidden, resp.Message)
+		return
+	}
+
+	balance, ok := s.charge(1)
+	if !ok {
+		writeError(rw, http.StatusForbidden, "Insufficient balance")
+		return
+	}
+
+	rw.Header().Set("X-Kickbox-Balance", strconv.Itoa(balance))
+	rw.Header().Set("X-Kickbox-Response-Time", strconv.FormatInt(time.Since(start).Milliseconds(), 10))
+	writeJSON(rw, http.StatusOK, resp)
+}
+
+func (s *Server) verifyBatch(rw http.ResponseWriter, r *http.Request) {
+	reader := csv.NewReader(r.Body)
+	reader.FieldsPerRecord = -1
+
+	var results []kickbox.ResponseVerify
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, "Invalid CSV file: "+err.Error())
+			return
+		}
+		// header rows and empty lines are skipped
+		if len(record) == 0 || !strings.Contains(record[0], "@") {
+			continue
+		}
+
+		_, resp, err := s.sandbox.Verify(r.Context(), strings.TrimSpace(record[0]))
+		if err != nil {
+			writeError(rw, http.StatusInternalServerError, err.Error())
+			return
+		}
+		results = append(results, *resp)
+	}
+
+	if len(results) == 0 {
+		writeError(rw, http.StatusBadRequest, "No email addresses found")
+		return
+	}
+
+	balance, ok := s.charge(len(results))
+	if !ok {
+		writeError(rw, http.StatusForbidden, "Insufficient balance")
+		return
+	}
+
+	s.mu.Lock()
+	j := &job{
+		id:        s.nextID,
+		name:      r.Header.Get("X-Kickbox-Filename"),
+		callback:  r.Header.Get("X-Kickbox-Callback"),
+		createdAt: s.now(),
+		results:   results,
+	}
+	if j.name == "" {
+		j.name = "Batch API Process - " + j.createdAt.UTC().Format("01-02-2006-15-04-05")
+	}
+	s.jobs[j.id] = j
+	s.nextID++
+	s.mu.Unlock()
+
+	rw.Header().Set("X-Kickbox-Balance", strconv.Itoa(balance))
+	writeJSON(rw, http.StatusOK, kickbox.ResponseVerifyBatch{
+		ID:      j.id,
+		Success: true,
+	})
+}
+
+func (s *Server) verifyBatchCheck(rw http.ResponseWriter, batchID string) {
+	id, err := strconv.Atoi(batchID)
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, "Invalid batch id")
+		return
+	}
+
+	s.mu.Lock()
+	j, found := s.jobs[id]
+	var status *kickbox.VerifyBatchCheckResponse
+	if found {
+		status = s.status(j)
+	}
+	s.mu.Unlock()
+
+	if !found {
+		writeError(rw, http.StatusNotFound, "Batch not found")
+		return
+	}
+
+	s.notifyCompleted()
+	writeJSON(rw, http.StatusOK, status)
+}
+
+// status computes the state of the job at the simulated time. Must be called with the lock held.
+func (s *Server) status(j *job) *kickbox.VerifyBatchCheckResponse {
+	resp := kickbox.VerifyBatchCheckResponse{
+		ID:      j.id,
+		Success: true,
+	}
+
+	elapsed := s.now().Sub(j.createdAt) - s.startingFor
+	switch {
+	case elapsed < 0:
+		resp.Status = kickbox.BatchStarting
+	case elapsed < s.processingFor:
+		resp.Status = kickbox.BatchProcessing
+		processed := int(float64(len(j.results)) * float64(elapsed) / float64(s.processingFor))
+		resp.Progress = j.results.Progress(processed)
+	default:
+		resp.Status = kickbox.BatchCompleted
+		resp.Name = j.name
+		resp.CreatedAt = j.createdAt.UTC().Format("2006-01-02T15:04:05.000Z")
+		resp.Duration = int(s.processingFor.Seconds())
+		resp.DownloadURL = fmt.Sprintf("%s/download/%d.csv", s.URL, j.id)
+		resp.Stats = j.results.Stats()
+	}
+
+	return &resp
+}
+
+// notifyCompleted posts the callback of the jobs completed since the last call
+func (s *Server) notifyCompleted() {
+	type notification struct {
+		url    string
+		status *kickbox.VerifyBatchCheckResponse
+	}
+
+	s.mu.Lock()
+	var pending []notification
+	for _, j := range s.jobs {
+		if j.callback == "" || j.notified {
+			continue
+		}
+		if status := s.status(j); status.Status == kickbox.BatchCompleted {
+			j.notified = true
+			pending = append(pending, notification{url: j.callback, status: status})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, n := range pending {
+		body, _ := json.Marshal(n.status)
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, n.url, bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if resp, err := s.callbackClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+func (s *Server) download(rw http.ResponseWriter, _ *http.Request, batchID string) {
+	id, _ := strconv.Atoi(batchID)
+
+	s.mu.Lock()
+	j, found := s.jobs[id]
+	completed := found && s.status(j).Status == kickbox.BatchCompleted
+	s.mu.Unlock()
+
+	if !completed {
+		writeError(rw, http.StatusNotFound, "Not found")
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/csv")
+	rw.WriteHeader(http.StatusOK)
+
+	_ = j.results.WriteCSV(rw)
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(v)
+}
+
+func writeError(rw http.ResponseWriter, status int, message string) {
+	writeJSON(rw, status, struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}{
+		Message: message,
+	})
+}
@@ -0,0 +1,170 @@
+package kickboxtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+	"github.com/wakumaku/kickbox/kickboxtest"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func newClient(t *testing.T, svr *kickboxtest.Server, opts ...kickbox.ClientHTTPOption) *kickbox.ClientHTTP {
+	opts = append([]kickbox.ClientHTTPOption{
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.CustomRateLimiter(rate.NewLimiter(rate.Inf, 1)),
+	}, opts...)
+
+	client, err := kickbox.New("apikey", opts...)
+	assert.Nil(t, err)
+	return client
+}
+
+func TestServerVerify(t *testing.T) {
+	svr := kickboxtest.NewServer(kickboxtest.WithBalance(2))
+	defer svr.Close()
+
+	client := newClient(t, svr)
+
+	header, resp, err := client.Verify(context.TODO(), "user+undeliverable@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, header.HTTPStatus)
+	assert.Equal(t, 1, header.Balance)
+	assert.Equal(t, kickbox.ResultUndeliverable, resp.Result)
+	assert.Equal(t, kickbox.ReasonRejectedEmail, resp.Reason)
+	assert.Equal(t, "user+undeliverable@example.com", resp.Email)
+
+	_, _, err = client.Verify(context.TODO(), "insufficient-balance@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrInsufficientBalance))
+
+	header, _, err = client.Verify(context.TODO(), "deliverable@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, header.Balance)
+
+	_, _, err = client.Verify(context.TODO(), "deliverable@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrInsufficientBalance))
+	assert.Equal(t, 0, svr.Balance())
+	assert.Equal(t, 4, svr.Requests("/v2/verify"))
+}
+
+func TestServerAPIKey(t *testing.T) {
+	svr := kickboxtest.NewServer(kickboxtest.WithAPIKey("secret"))
+	defer svr.Close()
+
+	_, _, err := newClient(t, svr).Verify(context.TODO(), "deliverable@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrUnauthorized))
+
+	_, err = newClient(t, svr).VerifyBatchCheck(context.TODO(), "1")
+	assert.True(t, errors.Is(err, kickbox.ErrUnauthorized))
+
+	client, err := kickbox.New("secret",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.HeaderAuthentication(),
+	)
+	assert.Nil(t, err)
+	_, _, err = client.Verify(context.TODO(), "deliverable@example.com")
+	assert.Nil(t, err)
+}
+
+func TestServerBatchLifecycle(t *testing.T) {
+	svr := kickboxtest.NewServer(kickboxtest.WithBatchTiming(time.Minute, 10*time.Minute))
+	defer svr.Close()
+
+	callbacks := make(chan *kickbox.VerifyBatchCheckResponse, 1)
+	callbackSvr := httptest.NewServer(kickbox.CallbackHandler(func(_ context.Context, resp *kickbox.VerifyBatchCheckResponse) error {
+		callbacks <- resp
+		return nil
+	}))
+	defer callbackSvr.Close()
+
+	client := newClient(t, svr)
+
+	emailsFile, err := os.Open("../testdata/sample.csv")
+	assert.Nil(t, err)
+
+	batch, err := client.VerifyBatch(context.TODO(), emailsFile,
+		kickbox.Filename("sample.csv"),
+		kickbox.Callback(callbackSvr.URL),
+	)
+	assert.Nil(t, err)
+	assert.True(t, batch.Success)
+	assert.Equal(t, 1000-15, svr.Balance())
+
+	batchID := "1"
+	check, err := client.VerifyBatchCheck(context.TODO(), batchID)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchStarting, check.Status)
+
+	svr.Advance(6 * time.Minute)
+	check, err = client.VerifyBatchCheck(context.TODO(), batchID)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchProcessing, check.Status)
+	assert.Equal(t, kickbox.BatchProgress{Deliverable: 7, Total: 15, Unprocessed: 8}, check.Progress)
+
+	svr.Advance(5 * time.Minute)
+	check, err = client.VerifyBatchCheck(context.TODO(), batchID)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchCompleted, check.Status)
+	assert.Equal(t, "sample.csv", check.Name)
+	assert.Equal(t, kickbox.BatchStats{Deliverable: 15, Sendex: 1, Addresses: 15}, check.Stats)
+
+	select {
+	case notified := <-callbacks:
+		assert.Equal(t, check, notified)
+	default:
+		t.Error("callback not received")
+	}
+
+	results, err := client.DownloadBatchResults(context.TODO(), check)
+	assert.Nil(t, err)
+	defer results.Close()
+
+	total := 0
+	for results.Next() {
+		total++
+		assert.Equal(t, kickbox.ResultDeliverable, results.Result().Result)
+		assert.Equal(t, "example.com", results.Result().Domain)
+	}
+	assert.Nil(t, results.Err())
+	assert.Equal(t, 15, total)
+
+	_, err = client.VerifyBatchCheck(context.TODO(), "42")
+	var apiErr *kickbox.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.HTTPStatus)
+}
+
+func TestServerFaults(t *testing.T) {
+	svr := kickboxtest.NewServer()
+	defer svr.Close()
+
+	client := newClient(t, svr, kickbox.WithRetryPolicy(kickbox.RetryPolicy{MaxAttempts: 3}))
+
+	// transient failures are recovered by the client retry policy
+	svr.InjectFault(kickboxtest.Fault{Path: "/v2/verify", Status: http.StatusTooManyRequests, RetryAfter: "0", Times: 1})
+	svr.InjectFault(kickboxtest.Fault{Path: "/v2/verify", Status: http.StatusBadGateway, Times: 1})
+	_, resp, err := client.Verify(context.TODO(), "deliverable@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultDeliverable, resp.Result)
+	assert.Equal(t, 3, svr.Requests("/v2/verify"))
+
+	svr.InjectFault(kickboxtest.Fault{Status: http.StatusServiceUnavailable})
+	_, _, err = client.Verify(context.TODO(), "deliverable@example.com")
+	assert.True(t, errors.Is(err, kickbox.ErrServerError))
+	svr.ClearFaults()
+
+	svr.InjectFault(kickboxtest.Fault{Malformed: true, Times: 1})
+	_, _, err = client.Verify(context.TODO(), "deliverable@example.com")
+	assert.EqualError(t, err, "decoding response: unexpected EOF")
+
+	svr.InjectFault(kickboxtest.Fault{Latency: time.Second, Times: 1})
+	_, _, err = newClient(t, svr).Verify(context.TODO(), "deliverable@example.com", kickbox.Timeout(50*time.Millisecond))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
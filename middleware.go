@@ -0,0 +1,202 @@
+package kickbox
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Middleware decorates a Verifier with additional behavior
+type Middleware func(Verifier) Verifier
+
+// Chain composes the middlewares into one, the first one being the outermost:
+// Chain(a, b)(v) is a(b(v))
+func Chain(middlewares ...Middleware) Middleware {
+	return func(v Verifier) Verifier {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			v = middlewares[i](v)
+		}
+		return v
+	}
+}
+
+// verifyMiddleware replaces the Verify method of a Verifier, the batch methods are delegated
+type verifyMiddleware struct {
+	Verifier
+	verify verifyFunc
+}
+
+// Verify calls the decorated verification
+func (m *verifyMiddleware) Verify(ctx context.Context, email string, opts ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error) {
+	return m.verify(ctx, email, opts...)
+}
+
+// LoggingOptions holds the optional parameters of LoggingMiddleware
+type LoggingOptions struct {
+	logEmailAddresses bool
+}
+
+// LoggingOption option type
+type LoggingOption func(*LoggingOptions)
+
+// LogEmailAddresses logs the email addresses in full, instead of their domain only
+func LogEmailAddresses() LoggingOption {
+	return func(o *LoggingOptions) {
+		o.logEmailAddresses = true
+	}
+}
+
+// LoggingMiddleware logs every request with its outcome and duration. The local part of
+// the email addresses is redacted, from the errors too, unless LogEmailAddresses is set.
+func LoggingMiddleware(logger *log.Logger, opts ...LoggingOption) Middleware {
+	options := LoggingOptions{}
+	for _, apply := range opts {
+		apply(&options)
+	}
+	return func(next Verifier) Verifier {
+		return &loggingVerifier{Verifier: next, logger: logger, options: options}
+	}
+}
+
+// loggingVerifier is the Verifier decorated by LoggingMiddleware
+type loggingVerifier struct {
+	Verifier
+	logger  *log.Logger
+	options LoggingOptions
+}
+
+func (l *loggingVerifier) Verify(ctx context.Context, email string, opts ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error) {
+	start := time.Now()
+	header, resp, err := l.Verifier.Verify(ctx, email, opts...)
+
+	logged, msg := email, ""
+	if err != nil {
+		msg = err.Error()
+	}
+	if !l.options.logEmailAddresses && email != "" {
+		logged = redactEmail(email)
+		msg = strings.ReplaceAll(msg, email, logged)
+		msg = strings.ReplaceAll(msg, url.QueryEscape(email), url.QueryEscape(logged))
+	}
+
+	switch {
+	case err != nil:
+		l.logger.Printf("kickbox: verify %s: error after %v: %s", logged, time.Since(start), msg)
+	case resp == nil:
+		l.logger.Printf("kickbox: verify %s: no response in %v", logged, time.Since(start))
+	default:
+		cached := header != nil && header.Cached
+		l.logger.Printf("kickbox: verify %s: %s %s in %v (cached: %t)", logged, resp.Result, resp.Reason, time.Since(start), cached)
+	}
+	return header, resp, err
+}
+
+// redactEmail hides the local part of the email, keeping the domain
+func redactEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return redacted
+	}
+	return redacted + email[at:]
+}
+
+func (l *loggingVerifier) VerifyBatch(ctx context.Context, file io.ReadCloser, opts ...VerifyBatchOption) (*ResponseVerifyBatch, error) {
+	start := time.Now()
+	resp, err := l.Verifier.VerifyBatch(ctx, file, opts...)
+	if err != nil {
+		l.logger.Printf("kickbox: verify batch: error after %v: %v", time.Since(start), err)
+	} else {
+		l.logger.Printf("kickbox: verify batch: job %d created in %v", resp.ID, time.Since(start))
+	}
+	return resp, err
+}
+
+func (l *loggingVerifier) VerifyBatchCheck(ctx context.Context, batchID string) (*VerifyBatchCheckResponse, error) {
+	start := time.Now()
+	resp, err := l.Verifier.VerifyBatchCheck(ctx, batchID)
+	if err != nil {
+		l.logger.Printf("kickbox: verify batch check %s: error after %v: %v", batchID, time.Since(start), err)
+	} else {
+		l.logger.Printf("kickbox: verify batch check %s: %s in %v", batchID, resp.Status, time.Since(start))
+	}
+	return resp, err
+}
+
+// VerifyMetric describes a verification request, as reported by MetricsMiddleware
+type VerifyMetric struct {
+	Result     Result        // empty on error
+	Reason     Reason        // empty on error
+	HTTPStatus int           // zero when no response was received
+	Cached     bool          // served by a cache, see WithCache
+	Duration   time.Duration // time spent verifying
+	Err        error         // request error, if any
+}
+
+// MetricsMiddleware reports every verification to observe, e.g. to feed counters and histograms
+func MetricsMiddleware(observe func(VerifyMetric)) Middleware {
+	return func(next Verifier) Verifier {
+		return &verifyMiddleware{
+			Verifier: next,
+			verify: func(ctx context.Context, email string, opts ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error) {
+				start := time.Now()
+				header, resp, err := next.Verify(ctx, email, opts...)
+
+				metric := VerifyMetric{Duration: time.Since(start), Err: err}
+				if header != nil {
+					metric.HTTPStatus = header.HTTPStatus
+					metric.Cached = header.Cached
+				}
+				if err == nil && resp != nil {
+					metric.Result = resp.Result
+					metric.Reason = resp.Reason
+				}
+				observe(metric)
+
+				return header, resp, err
+			},
+		}
+	}
+}
+
+// CacheMiddleware serves the verifications from the cache, see WithCache
+func CacheMiddleware(cache Cache, ttl CacheTTL) Middleware {
+	return func(next Verifier) Verifier {
+		return WithCache(next, cache, ttl)
+	}
+}
+
+// RetryMiddleware retries the failed verifications following the policy, see WithRetryPolicy.
+// Batch requests are not retried as their file can not be replayed.
+func RetryMiddleware(p RetryPolicy) Middleware {
+	return func(next Verifier) Verifier {
+		return &verifyMiddleware{
+			Verifier: next,
+			verify: func(ctx context.Context, email string, opts ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error) {
+				var (
+					header *ResponseVerifyHeaders
+					resp   *ResponseVerify
+				)
+				err := retry(ctx, p, true, func(_ int) error {
+					var err error
+					header, resp, err = next.Verify(ctx, email, opts...)
+					return err
+				})
+				return header, resp, err
+			},
+		}
+	}
+}
+
+// DryRunMiddleware answers every request with the sandbox instead of the decorated
+// Verifier, a new sandbox with the default rules is used when nil
+func DryRunMiddleware(sandbox *ClientSandbox) Middleware {
+	if sandbox == nil {
+		sandbox = NewSandbox()
+	}
+	return func(Verifier) Verifier {
+		return sandbox
+	}
+}
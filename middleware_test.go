@@ -0,0 +1,159 @@
+package kickbox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tracingMiddleware records its name on every Verify call
+func tracingMiddleware(name string, trace *[]string) kickbox.Middleware {
+	return func(next kickbox.Verifier) kickbox.Verifier {
+		return kickbox.Chain(kickbox.MetricsMiddleware(func(kickbox.VerifyMetric) {
+			*trace = append(*trace, name)
+		}))(next)
+	}
+}
+
+func TestChain(t *testing.T) {
+	var trace []string
+	verifier := kickbox.Chain(
+		tracingMiddleware("outer", &trace),
+		tracingMiddleware("inner", &trace),
+	)(kickbox.NewSandbox())
+
+	_, resp, err := verifier.Verify(context.TODO(), "role@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultRisky, resp.Result)
+	// the inner middleware returns first
+	assert.Equal(t, []string{"inner", "outer"}, trace)
+
+	// no middlewares
+	assert.IsType(t, &kickbox.ClientSandbox{}, kickbox.Chain()(kickbox.NewSandbox()))
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	verifier := kickbox.LoggingMiddleware(log.New(&buf, "", 0))(kickbox.NewSandbox(
+		kickbox.PrependSandboxRules(kickbox.SandboxRule{
+			Match: kickbox.MatchDomain("down.com"),
+			Err:   errors.New("boom"),
+		}),
+	))
+
+	// the local part is redacted by default
+	_, _, _ = verifier.Verify(context.TODO(), "undeliverable@example.com")
+	assert.Contains(t, buf.String(), "kickbox: verify REDACTED@example.com: undeliverable rejected_email in ")
+
+	_, _, _ = verifier.Verify(context.TODO(), "user@down.com")
+	assert.Contains(t, buf.String(), "kickbox: verify REDACTED@down.com: error after ")
+	assert.Contains(t, buf.String(), ": boom\n")
+	assert.NotContains(t, buf.String(), "undeliverable@")
+
+	emailsFile, err := os.Open("./testdata/sample.csv")
+	assert.Nil(t, err)
+	batch, err := verifier.VerifyBatch(context.TODO(), emailsFile)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "kickbox: verify batch: job 123456 created in ")
+
+	_, err = verifier.VerifyBatchCheck(context.TODO(), "123456")
+	assert.Nil(t, err)
+	assert.Equal(t, 123456, batch.ID)
+	assert.Contains(t, buf.String(), "kickbox: verify batch check 123456: completed in ")
+}
+
+func TestLoggingMiddlewareEmails(t *testing.T) {
+	var buf bytes.Buffer
+	sandbox := kickbox.NewSandbox(kickbox.PrependSandboxRules(kickbox.SandboxRule{
+		Match: kickbox.MatchDomain("down.com"),
+		Err:   &url.Error{Op: "Get", URL: "https://api.kickbox.com/v2/verify?email=john.doe%2Btag%40down.com", Err: errors.New("boom from john.doe+tag@down.com")},
+	}))
+
+	_, _, _ = kickbox.LoggingMiddleware(log.New(&buf, "", 0))(sandbox).Verify(context.TODO(), "john.doe+tag@down.com")
+	assert.Contains(t, buf.String(), "kickbox: verify REDACTED@down.com: error after ")
+	assert.NotContains(t, buf.String(), "john.doe")
+
+	// opt-in
+	buf.Reset()
+	_, _, _ = kickbox.LoggingMiddleware(log.New(&buf, "", 0), kickbox.LogEmailAddresses())(sandbox).Verify(context.TODO(), "deliverable@example.com")
+	assert.Contains(t, buf.String(), "kickbox: verify deliverable@example.com: deliverable accepted_email in ")
+}
+
+func TestLoggingMiddlewareNilResponse(t *testing.T) {
+	var buf bytes.Buffer
+	verifier := kickbox.LoggingMiddleware(log.New(&buf, "", 0))(nilVerifier{})
+
+	header, resp, err := verifier.Verify(context.TODO(), "user@example.com")
+	assert.Nil(t, header)
+	assert.Nil(t, resp)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "kickbox: verify REDACTED@example.com: no response in ")
+}
+
+// nilVerifier answers every verification without a response nor an error
+type nilVerifier struct {
+	kickbox.Verifier
+}
+
+func (nilVerifier) Verify(context.Context, string, ...kickbox.VerifyOption) (*kickbox.ResponseVerifyHeaders, *kickbox.ResponseVerify, error) {
+	return nil, nil, nil
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	var metrics []kickbox.VerifyMetric
+	cache, err := kickbox.NewLRUCache(10)
+	assert.Nil(t, err)
+
+	verifier := kickbox.Chain(
+		kickbox.MetricsMiddleware(func(m kickbox.VerifyMetric) { metrics = append(metrics, m) }),
+		kickbox.CacheMiddleware(cache, nil),
+	)(kickbox.NewSandbox())
+
+	for i := 0; i < 2; i++ {
+		_, _, err := verifier.Verify(context.TODO(), "timeout@example.com")
+		assert.Nil(t, err)
+	}
+
+	assert.Len(t, metrics, 2)
+	assert.Equal(t, kickbox.ResultUnknown, metrics[0].Result)
+	assert.Equal(t, kickbox.ReasonTimeout, metrics[0].Reason)
+	assert.Equal(t, http.StatusOK, metrics[0].HTTPStatus)
+	assert.False(t, metrics[0].Cached)
+	assert.True(t, metrics[1].Cached)
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	svr, calls := flakyServer(2, http.StatusBadGateway, nil)
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey", kickbox.OverrideBaseURL(svr.URL))
+	assert.Nil(t, err)
+
+	verifier := kickbox.RetryMiddleware(kickbox.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond})(client)
+	_, resp, err := verifier.Verify(context.TODO(), "user@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultDeliverable, resp.Result)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestDryRunMiddleware(t *testing.T) {
+	client, err := kickbox.New("apikey", kickbox.OverrideBaseURL("http://nonexistinghost.test.me"))
+	assert.Nil(t, err)
+
+	verifier := kickbox.DryRunMiddleware(nil)(client)
+	_, resp, err := verifier.Verify(context.TODO(), "role@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultRisky, resp.Result)
+	assert.True(t, resp.Role)
+}
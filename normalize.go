@@ -0,0 +1,147 @@
+package kickbox
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeOptions holds the optional rules applied by Normalize
+type NormalizeOptions struct {
+	lowercaseLocalPart bool
+	unicodeDomain      bool
+	stripPlusTag       bool
+	providerRules      bool
+}
+
+// NormalizeOption option type
+type NormalizeOption func(*NormalizeOptions)
+
+// LowercaseLocalPart lowercases the local part too. Most providers treat it
+// case insensitive, although RFC 5321 does not require it.
+func LowercaseLocalPart() NormalizeOption {
+	return func(o *NormalizeOptions) {
+		o.lowercaseLocalPart = true
+	}
+}
+
+// UnicodeDomain returns the domain in Unicode, e.g. bücher.de, instead of punycode, e.g. xn--bcher-kva.de
+func UnicodeDomain() NormalizeOption {
+	return func(o *NormalizeOptions) {
+		o.unicodeDomain = true
+	}
+}
+
+// StripPlusTag removes the subaddress of every domain, e.g. user+tag@example.com to user@example.com
+func StripPlusTag() NormalizeOption {
+	return func(o *NormalizeOptions) {
+		o.stripPlusTag = true
+	}
+}
+
+// ProviderRules applies the address equivalences of well known providers:
+// googlemail.com is gmail.com, gmail ignores the dots of the local part and
+// gmail, outlook, icloud, fastmail and proton ignore the plus tag.
+// The local part of these providers is lowercased.
+func ProviderRules() NormalizeOption {
+	return func(o *NormalizeOptions) {
+		o.providerRules = true
+	}
+}
+
+// plusTagProviders are the domains delivering user+tag@ to user@
+var plusTagProviders = map[string]bool{
+	"gmail.com":      true,
+	"outlook.com":    true,
+	"hotmail.com":    true,
+	"live.com":       true,
+	"icloud.com":     true,
+	"me.com":         true,
+	"fastmail.com":   true,
+	"protonmail.com": true,
+	"proton.me":      true,
+}
+
+// Normalize returns the canonical form of the email: whitespace and angle brackets
+// trimmed, e.g. " <User@Example.COM> " to User@example.com, and the domain lowercased
+// in punycode, mapped and NFC normalized as per UTS #46. The options apply further
+// rules, see LowercaseLocalPart and ProviderRules.
+// A *SyntaxError is returned when the email has no local part or domain.
+func Normalize(email string, opts ...NormalizeOption) (string, error) {
+	options := NormalizeOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	address := strings.TrimSpace(email)
+	// "Name <user@example.com>" or "<user@example.com>"
+	if strings.HasSuffix(address, ">") {
+		if i := strings.LastIndex(address, "<"); i >= 0 {
+			address = strings.TrimSpace(address[i+1 : len(address)-1])
+		}
+	}
+
+	at := strings.LastIndex(address, "@")
+	switch {
+	case address == "":
+		return "", &SyntaxError{Email: email, Reason: SyntaxEmpty}
+	case at < 0:
+		return "", &SyntaxError{Email: email, Reason: SyntaxMissingAt}
+	case at == 0:
+		return "", &SyntaxError{Email: email, Reason: SyntaxLocalPartEmpty}
+	case at == len(address)-1:
+		return "", &SyntaxError{Email: email, Reason: SyntaxDomainEmpty}
+	}
+	local, domain := address[:at], strings.TrimSuffix(strings.ToLower(address[at+1:]), ".")
+
+	convert := idna.Lookup.ToASCII
+	if options.unicodeDomain {
+		convert = idna.Lookup.ToUnicode
+	}
+	converted, err := convert(domain)
+	if err != nil {
+		return "", fmt.Errorf("normalizing domain %q: %v", domain, err)
+	}
+	domain = converted
+
+	if options.lowercaseLocalPart {
+		local = strings.ToLower(local)
+	}
+
+	if options.providerRules {
+		if domain == "googlemail.com" {
+			domain = "gmail.com"
+		}
+		if domain == "gmail.com" {
+			local = strings.ReplaceAll(local, ".", "")
+		}
+		if plusTagProviders[domain] {
+			local = strings.ToLower(stripPlusTag(local))
+		}
+	}
+	if options.stripPlusTag {
+		local = stripPlusTag(local)
+	}
+
+	return local + "@" + domain, nil
+}
+
+// EmailKey returns the key identifying an email, used to detect duplicates and as cache key.
+// It is the normalized email with the local part lowercased, see Normalize.
+// Emails failing to normalize are only trimmed and lowercased.
+func EmailKey(email string) string {
+	key, err := Normalize(email, LowercaseLocalPart())
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(email))
+	}
+	return key
+}
+
+// stripPlusTag removes the text after the first + of the local part, if any
+func stripPlusTag(local string) string {
+	if i := strings.Index(local, "+"); i > 0 {
+		return local[:i]
+	}
+	return local
+}
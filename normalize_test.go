@@ -0,0 +1,85 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		email string
+		opts  []kickbox.NormalizeOption
+		want  string
+	}{
+		{" Foo@Example.COM ", nil, "Foo@example.com"},
+		{"<foo@example.com>", nil, "foo@example.com"},
+		{"Foo Bar <Foo@Example.com.>", nil, "Foo@example.com"},
+		{"Foo@Example.COM", []kickbox.NormalizeOption{kickbox.LowercaseLocalPart()}, "foo@example.com"},
+		{"user@Bücher.DE", nil, "user@xn--bcher-kva.de"},
+		{"user@XN--BCHER-KVA.de", []kickbox.NormalizeOption{kickbox.UnicodeDomain()}, "user@bücher.de"},
+		{"user@日本語.jp", nil, "user@xn--wgv71a119e.jp"},
+		{"user@bu\u0308cher.de", nil, "user@xn--bcher-kva.de"}, // NFD
+		{"user@ｂücher.de", nil, "user@xn--bcher-kva.de"},       // fullwidth
+		{"user+news@example.com", []kickbox.NormalizeOption{kickbox.StripPlusTag()}, "user@example.com"},
+		{"User+news@example.com", []kickbox.NormalizeOption{kickbox.ProviderRules()}, "User+news@example.com"},
+		{"First.Last+news@GoogleMail.com", []kickbox.NormalizeOption{kickbox.ProviderRules()}, "firstlast@gmail.com"},
+		{"first.last+news@outlook.com", []kickbox.NormalizeOption{kickbox.ProviderRules()}, "first.last@outlook.com"},
+	}
+	for _, tt := range tests {
+		got, err := kickbox.Normalize(tt.email, tt.opts...)
+		assert.Nil(t, err, tt.email)
+		assert.Equal(t, tt.want, got, tt.email)
+	}
+
+	invalid := map[string]kickbox.SyntaxReason{
+		"   ":          kickbox.SyntaxEmpty,
+		"<>":           kickbox.SyntaxEmpty,
+		"example.com":  kickbox.SyntaxMissingAt,
+		"@example.com": kickbox.SyntaxLocalPartEmpty,
+		"user@":        kickbox.SyntaxDomainEmpty,
+	}
+	for email, reason := range invalid {
+		_, err := kickbox.Normalize(email)
+		var syntaxErr *kickbox.SyntaxError
+		if assert.True(t, errors.As(err, &syntaxErr), email) {
+			assert.Equal(t, reason, syntaxErr.Reason, email)
+		}
+	}
+
+	got, err := kickbox.Normalize("user@xn--mnchen-3ya.example", kickbox.UnicodeDomain())
+	assert.Nil(t, err)
+	assert.Equal(t, "user@münchen.example", got)
+	_, err = kickbox.Normalize("user@xn--99zz.example", kickbox.UnicodeDomain())
+	assert.NotNil(t, err)
+}
+
+func TestEmailKey(t *testing.T) {
+	assert.Equal(t, "user@xn--bcher-kva.de", kickbox.EmailKey(" <User@Bücher.DE> "))
+	assert.Equal(t, "user+tag@example.com", kickbox.EmailKey("User+Tag@Example.com"))
+	// not normalized
+	assert.Equal(t, "example.com", kickbox.EmailKey(" Example.COM "))
+}
+
+func TestVerifyManyDeduplicateCanonical(t *testing.T) {
+	client := kickbox.NewSandbox()
+
+	emails := []string{"deliverable@bücher.de", " Deliverable@XN--BCHER-KVA.de", "<deliverable@Bücher.de>"}
+	var results []kickbox.VerifyManyResult
+	var progress kickbox.VerifyManyProgress
+	for r := range client.VerifyMany(context.TODO(), emails,
+		kickbox.Deduplicate(),
+		kickbox.OnProgress(func(p kickbox.VerifyManyProgress) { progress = p }),
+	) {
+		results = append(results, r)
+	}
+
+	assert.Len(t, results, 1)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, "deliverable@xn--bcher-kva.de", results[0].Response.Email)
+	assert.Equal(t, 2, progress.Duplicates)
+}
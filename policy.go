@@ -0,0 +1,224 @@
+package kickbox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Decision is the outcome of evaluating a verification response against a Policy
+type Decision string
+
+// Policy decisions
+const (
+	DecisionAccept Decision = "accept" // let the email through
+	DecisionReject Decision = "reject" // block the email
+	DecisionReview Decision = "review" // needs a human or a later verification
+)
+
+// String implements the fmt.Stringer interface
+func (d Decision) String() string {
+	return string(d)
+}
+
+// valid reports whether d is one of the policy decisions
+func (d Decision) valid() bool {
+	return d == DecisionAccept || d == DecisionReject || d == DecisionReview
+}
+
+// PolicyRule decides on the responses matching all its conditions, unset conditions match any response
+type PolicyRule struct {
+	Name     string   `json:"name" yaml:"name"`
+	Decision Decision `json:"decision" yaml:"decision"`
+	// Soft flags the decision as a weak one, e.g. accepting accept-all domains
+	Soft bool `json:"soft,omitempty" yaml:"soft,omitempty"`
+
+	Result        []Result `json:"result,omitempty" yaml:"result,omitempty"`                   // any of the results
+	Reason        []Reason `json:"reason,omitempty" yaml:"reason,omitempty"`                   // any of the reasons
+	SendexAtLeast *float64 `json:"sendex_at_least,omitempty" yaml:"sendex_at_least,omitempty"` // sendex >= value
+	SendexBelow   *float64 `json:"sendex_below,omitempty" yaml:"sendex_below,omitempty"`       // sendex < value
+	Role          *bool    `json:"role,omitempty" yaml:"role,omitempty"`
+	Free          *bool    `json:"free,omitempty" yaml:"free,omitempty"`
+	Disposable    *bool    `json:"disposable,omitempty" yaml:"disposable,omitempty"`
+	AcceptAll     *bool    `json:"accept_all,omitempty" yaml:"accept_all,omitempty"`
+}
+
+// Policy decides whether a verified email is accepted, rejected or needs review.
+// The rules are evaluated in order, the first matching rule decides.
+type Policy struct {
+	Rules   []PolicyRule `json:"rules" yaml:"rules"`
+	Default Decision     `json:"default,omitempty" yaml:"default,omitempty"` // when no rule matches, Default: review
+}
+
+// PolicyDecision is the result of a policy evaluation
+type PolicyDecision struct {
+	Decision Decision // accept, reject or review
+	Rule     string   // name of the rule deciding, "default" when none matched
+	Soft     bool     // the rule deciding is soft
+}
+
+// defaultRuleName is the Rule of the decisions taken by the policy default
+const defaultRuleName = "default"
+
+// DefaultPolicy rejects undeliverable and disposable emails, accepts the deliverable ones
+// and the risky ones with a sendex of 0.6 or more, soft accepts accept-all domains and
+// sends everything else to review
+func DefaultPolicy() *Policy {
+	yes, goodSendex := true, 0.6
+	return &Policy{
+		Rules: []PolicyRule{
+			{Name: "reject-undeliverable", Decision: DecisionReject, Result: []Result{ResultUndeliverable}},
+			{Name: "reject-disposable", Decision: DecisionReject, Disposable: &yes},
+			{Name: "accept-deliverable", Decision: DecisionAccept, Result: []Result{ResultDeliverable}},
+			{Name: "soft-accept-all", Decision: DecisionAccept, Soft: true, Result: []Result{ResultRisky}, AcceptAll: &yes},
+			{Name: "accept-risky-good-sendex", Decision: DecisionAccept, Result: []Result{ResultRisky}, SendexAtLeast: &goodSendex},
+		},
+		Default: DecisionReview,
+	}
+}
+
+// ParsePolicy decodes a policy in YAML or JSON, JSON being valid YAML, and validates it.
+// Unknown fields are rejected to catch typos in the rules.
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("decoding policy: empty document")
+		}
+		return nil, fmt.Errorf("decoding policy: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadPolicy reads a YAML or JSON policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy: %v", err)
+	}
+	return ParsePolicy(data)
+}
+
+// Validate checks the decisions, results, reasons and sendex ranges of the rules
+func (p *Policy) Validate() error {
+	if p.Default != "" && !p.Default.valid() {
+		return fmt.Errorf("invalid policy: default decision %q", p.Default)
+	}
+	for i, rule := range p.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("invalid policy: rule %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the rule is consistent
+func (r *PolicyRule) validate() error {
+	if !r.Decision.valid() {
+		return fmt.Errorf("decision %q", r.Decision)
+	}
+	for _, result := range r.Result {
+		if !result.IsKnown() {
+			return fmt.Errorf("result %q", result)
+		}
+	}
+	for _, reason := range r.Reason {
+		if !reason.IsKnown() {
+			return fmt.Errorf("reason %q", reason)
+		}
+	}
+	for _, sendex := range []*float64{r.SendexAtLeast, r.SendexBelow} {
+		if sendex != nil && (*sendex < 0 || *sendex > 1) {
+			return fmt.Errorf("sendex %v out of range 0..1", *sendex)
+		}
+	}
+	if r.SendexAtLeast != nil && r.SendexBelow != nil && *r.SendexAtLeast >= *r.SendexBelow {
+		return fmt.Errorf("sendex range [%v, %v) is empty", *r.SendexAtLeast, *r.SendexBelow)
+	}
+	return nil
+}
+
+// Evaluate decides on the verification response, the first matching rule wins.
+// A missing response matches no rule and gets the default decision.
+func (p *Policy) Evaluate(resp *ResponseVerify) PolicyDecision {
+	if resp == nil {
+		return p.defaultDecision()
+	}
+	for i, rule := range p.Rules {
+		if !rule.matches(resp) {
+			continue
+		}
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		return PolicyDecision{Decision: rule.Decision, Rule: name, Soft: rule.Soft}
+	}
+	return p.defaultDecision()
+}
+
+// defaultDecision is the decision when no rule matches, review unless set
+func (p *Policy) defaultDecision() PolicyDecision {
+	decision := p.Default
+	if decision == "" {
+		decision = DecisionReview
+	}
+	return PolicyDecision{Decision: decision, Rule: defaultRuleName}
+}
+
+// matches reports whether the response meets all the conditions of the rule
+func (r *PolicyRule) matches(resp *ResponseVerify) bool {
+	if len(r.Result) > 0 && !containsResult(r.Result, resp.Result) {
+		return false
+	}
+	if len(r.Reason) > 0 && !containsReason(r.Reason, resp.Reason) {
+		return false
+	}
+	if r.SendexAtLeast != nil && resp.Sendex < *r.SendexAtLeast {
+		return false
+	}
+	if r.SendexBelow != nil && resp.Sendex >= *r.SendexBelow {
+		return false
+	}
+	return matchFlag(r.Role, resp.Role) &&
+		matchFlag(r.Free, resp.Free) &&
+		matchFlag(r.Disposable, resp.Disposable) &&
+		matchFlag(r.AcceptAll, resp.AcceptAll)
+}
+
+// matchFlag reports whether the flag matches the condition, a nil condition matches both
+func matchFlag(condition *bool, flag bool) bool {
+	return condition == nil || *condition == flag
+}
+
+func containsResult(results []Result, result Result) bool {
+	for _, r := range results {
+		if r == result {
+			return true
+		}
+	}
+	return false
+}
+
+func containsReason(reasons []Reason, reason Reason) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package kickbox_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := kickbox.DefaultPolicy()
+	assert.Nil(t, policy.Validate())
+
+	tests := []struct {
+		resp kickbox.ResponseVerify
+		want kickbox.PolicyDecision
+	}{
+		{
+			resp: kickbox.ResponseVerify{Result: kickbox.ResultDeliverable, Sendex: 1},
+			want: kickbox.PolicyDecision{Decision: kickbox.DecisionAccept, Rule: "accept-deliverable"},
+		},
+		{
+			resp: kickbox.ResponseVerify{Result: kickbox.ResultUndeliverable, Reason: kickbox.ReasonRejectedEmail},
+			want: kickbox.PolicyDecision{Decision: kickbox.DecisionReject, Rule: "reject-undeliverable"},
+		},
+		{
+			resp: kickbox.ResponseVerify{Result: kickbox.ResultDeliverable, Disposable: true},
+			want: kickbox.PolicyDecision{Decision: kickbox.DecisionReject, Rule: "reject-disposable"},
+		},
+		{
+			resp: kickbox.ResponseVerify{Result: kickbox.ResultRisky, AcceptAll: true, Sendex: 0.2},
+			want: kickbox.PolicyDecision{Decision: kickbox.DecisionAccept, Rule: "soft-accept-all", Soft: true},
+		},
+		{
+			resp: kickbox.ResponseVerify{Result: kickbox.ResultRisky, Sendex: 0.6},
+			want: kickbox.PolicyDecision{Decision: kickbox.DecisionAccept, Rule: "accept-risky-good-sendex"},
+		},
+		{
+			resp: kickbox.ResponseVerify{Result: kickbox.ResultRisky, Sendex: 0.59},
+			want: kickbox.PolicyDecision{Decision: kickbox.DecisionReview, Rule: "default"},
+		},
+		{
+			resp: kickbox.ResponseVerify{Result: kickbox.ResultUnknown, Reason: kickbox.ReasonTimeout},
+			want: kickbox.PolicyDecision{Decision: kickbox.DecisionReview, Rule: "default"},
+		},
+	}
+	for _, tt := range tests {
+		resp := tt.resp
+		assert.Equal(t, tt.want, policy.Evaluate(&resp), "%+v", tt.resp)
+	}
+
+	// a missing response gets the default decision
+	assert.Equal(t, kickbox.PolicyDecision{Decision: kickbox.DecisionReview, Rule: "default"}, policy.Evaluate(nil))
+}
+
+func TestParsePolicy(t *testing.T) {
+	yamlPolicy := `
+rules:
+  - name: reject-role
+    decision: reject
+    role: true
+  - name: retry-later
+    decision: review
+    result: [unknown]
+    reason: [timeout, no_connect]
+  - decision: accept
+    result: [deliverable, risky]
+    sendex_at_least: 0.5
+    sendex_below: 0.9
+    soft: true
+default: reject
+`
+	jsonPolicy := `{
+  "rules": [
+    {"name": "reject-role", "decision": "reject", "role": true},
+    {"name": "retry-later", "decision": "review", "result": ["unknown"], "reason": ["timeout", "no_connect"]},
+    {"decision": "accept", "result": ["deliverable", "risky"], "sendex_at_least": 0.5, "sendex_below": 0.9, "soft": true}
+  ],
+  "default": "reject"
+}`
+
+	for _, data := range []string{yamlPolicy, jsonPolicy} {
+		policy, err := kickbox.ParsePolicy([]byte(data))
+		assert.Nil(t, err)
+		assert.Len(t, policy.Rules, 3)
+
+		decision := policy.Evaluate(&kickbox.ResponseVerify{Result: kickbox.ResultDeliverable, Role: true})
+		assert.Equal(t, kickbox.PolicyDecision{Decision: kickbox.DecisionReject, Rule: "reject-role"}, decision)
+
+		decision = policy.Evaluate(&kickbox.ResponseVerify{Result: kickbox.ResultUnknown, Reason: kickbox.ReasonNoConnect})
+		assert.Equal(t, kickbox.PolicyDecision{Decision: kickbox.DecisionReview, Rule: "retry-later"}, decision)
+
+		decision = policy.Evaluate(&kickbox.ResponseVerify{Result: kickbox.ResultRisky, Sendex: 0.7})
+		assert.Equal(t, kickbox.PolicyDecision{Decision: kickbox.DecisionAccept, Rule: "#3", Soft: true}, decision)
+
+		decision = policy.Evaluate(&kickbox.ResponseVerify{Result: kickbox.ResultDeliverable, Sendex: 0.9})
+		assert.Equal(t, kickbox.PolicyDecision{Decision: kickbox.DecisionReject, Rule: "default"}, decision)
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                                      "decoding policy: empty document",
+		"rules: [{decision: maybe}]":            "invalid policy: rule #1: decision \"maybe\"",
+		"rules: [{decision: accept, resul: x}]": "decoding policy: yaml: unmarshal errors:\n  line 1: field resul not found in type kickbox.PolicyRule",
+		"rules: [{name: r, decision: accept, result: [ok]}]":                          "invalid policy: rule r: result \"ok\"",
+		"rules: [{name: r, decision: accept, reason: [bad]}]":                         "invalid policy: rule r: reason \"bad\"",
+		"rules: [{name: r, decision: accept, sendex_at_least: 2}]":                    "invalid policy: rule r: sendex 2 out of range 0..1",
+		"rules: [{name: r, decision: accept, sendex_at_least: 1, sendex_below: 0.5}]": "invalid policy: rule r: sendex range [1, 0.5) is empty",
+		"default: allow": "invalid policy: default decision \"allow\"",
+	}
+	for data, want := range tests {
+		_, err := kickbox.ParsePolicy([]byte(data))
+		assert.EqualError(t, err, want, data)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("rules: [{name: all, decision: accept}]"), 0o600))
+
+	policy, err := kickbox.LoadPolicy(path)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.DecisionAccept, policy.Evaluate(&kickbox.ResponseVerify{}).Decision)
+
+	_, err = kickbox.LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.NotNil(t, err)
+}
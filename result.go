@@ -0,0 +1,185 @@
+package kickbox
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Result is the verification result of an email
+// see: https://docs.kickbox.com/docs/single-verification-api#the-response
+type Result string
+
+// Documented verification results
+const (
+	ResultDeliverable   Result = "deliverable"
+	ResultUndeliverable Result = "undeliverable"
+	ResultRisky         Result = "risky"
+	ResultUnknown       Result = "unknown"
+)
+
+// String implements the fmt.Stringer interface
+func (r Result) String() string {
+	return string(r)
+}
+
+// IsKnown reports whether r is a documented result
+func (r Result) IsKnown() bool {
+	switch r {
+	case ResultDeliverable, ResultUndeliverable, ResultRisky, ResultUnknown:
+		return true
+	}
+	return false
+}
+
+// IsDeliverable reports whether the email is a valid address that can be emailed
+func (r Result) IsDeliverable() bool {
+	return r == ResultDeliverable
+}
+
+// IsUndeliverable reports whether the email does not exist or is not valid
+func (r Result) IsUndeliverable() bool {
+	return r == ResultUndeliverable
+}
+
+// IsRisky reports whether the email has quality issues that may result in bounces or low engagement
+func (r Result) IsRisky() bool {
+	return r == ResultRisky
+}
+
+// IsUnknown reports whether the email server could not be reached to verify the email
+func (r Result) IsUnknown() bool {
+	return r == ResultUnknown
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (r Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(r))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, undocumented values are kept
+func (r *Result) UnmarshalJSON(data []byte) error {
+	s, err := unmarshalEnum(data)
+	if err != nil {
+		return fmt.Errorf("decoding result: %v", err)
+	}
+	*r = Result(s)
+	return nil
+}
+
+// Reason is the reason of the verification result
+// see: https://docs.kickbox.com/docs/single-verification-api#the-response
+type Reason string
+
+// Documented verification reasons
+const (
+	ReasonInvalidEmail      Reason = "invalid_email"      // specified email is not a valid email address syntax
+	ReasonInvalidDomain     Reason = "invalid_domain"     // domain for email does not exist
+	ReasonRejectedEmail     Reason = "rejected_email"     // email address was rejected by the SMTP server, email address does not exist
+	ReasonAcceptedEmail     Reason = "accepted_email"     // email address was accepted by the SMTP server
+	ReasonLowQuality        Reason = "low_quality"        // email address has quality issues that may make it a risky or low-value address
+	ReasonLowDeliverability Reason = "low_deliverability" // email address appears to be deliverable, but deliverability cannot be guaranteed
+	ReasonNoConnect         Reason = "no_connect"         // could not connect to SMTP server
+	ReasonTimeout           Reason = "timeout"            // SMTP session timed out
+	ReasonInvalidSMTP       Reason = "invalid_smtp"       // SMTP server returned an unexpected/invalid response
+	ReasonUnavailableSMTP   Reason = "unavailable_smtp"   // SMTP server was unavailable to process our request
+	ReasonUnexpectedError   Reason = "unexpected_error"   // an unexpected error has occurred
+)
+
+// String implements the fmt.Stringer interface
+func (r Reason) String() string {
+	return string(r)
+}
+
+// IsKnown reports whether r is a documented reason
+func (r Reason) IsKnown() bool {
+	switch r {
+	case ReasonInvalidEmail, ReasonInvalidDomain, ReasonRejectedEmail, ReasonAcceptedEmail,
+		ReasonLowQuality, ReasonLowDeliverability, ReasonNoConnect, ReasonTimeout,
+		ReasonInvalidSMTP, ReasonUnavailableSMTP, ReasonUnexpectedError:
+		return true
+	}
+	return false
+}
+
+// IsRetryable reports whether the reason is a temporary failure,
+// verifying the email again later may give a conclusive result
+func (r Reason) IsRetryable() bool {
+	switch r {
+	case ReasonNoConnect, ReasonTimeout, ReasonUnavailableSMTP, ReasonUnexpectedError:
+		return true
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (r Reason) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(r))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, undocumented values are kept
+func (r *Reason) UnmarshalJSON(data []byte) error {
+	s, err := unmarshalEnum(data)
+	if err != nil {
+		return fmt.Errorf("decoding reason: %v", err)
+	}
+	*r = Reason(s)
+	return nil
+}
+
+// BatchStatus is the status of a batch verification job
+// see: https://docs.kickbox.com/docs/batch-verification-api#the-response-1
+type BatchStatus string
+
+// Documented batch job statuses
+const (
+	BatchStarting   BatchStatus = "starting"
+	BatchProcessing BatchStatus = "processing"
+	BatchCompleted  BatchStatus = "completed"
+	BatchFailed     BatchStatus = "failed"
+)
+
+// String implements the fmt.Stringer interface
+func (s BatchStatus) String() string {
+	return string(s)
+}
+
+// IsKnown reports whether s is a documented status
+func (s BatchStatus) IsKnown() bool {
+	switch s {
+	case BatchStarting, BatchProcessing, BatchCompleted, BatchFailed:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether the job is done, completed or failed
+func (s BatchStatus) IsTerminal() bool {
+	return s == BatchCompleted || s == BatchFailed
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (s BatchStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(s))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, undocumented values are kept
+func (s *BatchStatus) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalEnum(data)
+	if err != nil {
+		return fmt.Errorf("decoding batch status: %v", err)
+	}
+	*s = BatchStatus(v)
+	return nil
+}
+
+// unmarshalEnum decodes a JSON string, null is decoded as empty
+func unmarshalEnum(data []byte) (string, error) {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", err
+	}
+	if s == nil {
+		return "", nil
+	}
+	return *s, nil
+}
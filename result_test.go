@@ -0,0 +1,71 @@
+package kickbox_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultJSON(t *testing.T) {
+	var resp kickbox.ResponseVerify
+	err := json.Unmarshal([]byte(`{"result":"risky","reason":"brand_new_reason"}`), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultRisky, resp.Result)
+	assert.True(t, resp.Result.IsRisky())
+	assert.True(t, resp.Result.IsKnown())
+
+	// undocumented values are kept
+	assert.Equal(t, kickbox.Reason("brand_new_reason"), resp.Reason)
+	assert.False(t, resp.Reason.IsKnown())
+
+	data, err := json.Marshal(resp)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"result":"risky","reason":"brand_new_reason"`)
+
+	err = json.Unmarshal([]byte(`{"result":null,"reason":null}`), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.Result(""), resp.Result)
+	assert.Equal(t, kickbox.Reason(""), resp.Reason)
+
+	err = json.Unmarshal([]byte(`{"result":1}`), &resp)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "decoding result")
+}
+
+func TestBatchStatusJSON(t *testing.T) {
+	var resp kickbox.VerifyBatchCheckResponse
+	err := json.Unmarshal([]byte(`{"id":1,"status":"completed"}`), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchCompleted, resp.Status)
+	assert.True(t, resp.Status.IsTerminal())
+	assert.Equal(t, "completed", resp.Status.String())
+
+	err = json.Unmarshal([]byte(`{"id":1,"status":"queued"}`), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.BatchStatus("queued"), resp.Status)
+	assert.False(t, resp.Status.IsKnown())
+	assert.False(t, resp.Status.IsTerminal())
+
+	err = json.Unmarshal([]byte(`{"id":1,"status":{}}`), &resp)
+	assert.NotNil(t, err)
+}
+
+func TestResultHelpers(t *testing.T) {
+	assert.True(t, kickbox.ResultDeliverable.IsDeliverable())
+	assert.True(t, kickbox.ResultUndeliverable.IsUndeliverable())
+	assert.True(t, kickbox.ResultUnknown.IsUnknown())
+	assert.False(t, kickbox.Result("other").IsKnown())
+
+	for _, reason := range []kickbox.Reason{kickbox.ReasonTimeout, kickbox.ReasonNoConnect, kickbox.ReasonUnavailableSMTP, kickbox.ReasonUnexpectedError} {
+		assert.True(t, reason.IsRetryable(), reason)
+	}
+	for _, reason := range []kickbox.Reason{kickbox.ReasonRejectedEmail, kickbox.ReasonInvalidDomain, kickbox.ReasonLowQuality} {
+		assert.False(t, reason.IsRetryable(), reason)
+	}
+
+	assert.True(t, kickbox.BatchFailed.IsTerminal())
+	assert.False(t, kickbox.BatchProcessing.IsTerminal())
+}
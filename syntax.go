@@ -0,0 +1,195 @@
+package kickbox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrInvalidSyntax classifies a *SyntaxError with errors.Is
+var ErrInvalidSyntax = errors.New("invalid email syntax")
+
+// SyntaxReason identifies why an email address is not syntactically valid
+type SyntaxReason string
+
+// Syntax reasons returned by ValidateSyntax
+const (
+	SyntaxEmpty             SyntaxReason = "empty"
+	SyntaxTooLong           SyntaxReason = "too_long"            // more than 254 octets
+	SyntaxMissingAt         SyntaxReason = "missing_at"          // no @ separating local part and domain
+	SyntaxLocalPartEmpty    SyntaxReason = "local_part_empty"    // nothing before the @
+	SyntaxLocalPartTooLong  SyntaxReason = "local_part_too_long" // more than 64 octets
+	SyntaxLocalPartInvalid  SyntaxReason = "local_part_invalid"  // bad characters, dots or quoting
+	SyntaxDomainEmpty       SyntaxReason = "domain_empty"        // nothing after the @
+	SyntaxDomainUnqualified SyntaxReason = "domain_unqualified"  // a single label, e.g. localhost
+	SyntaxDomainLabelEmpty  SyntaxReason = "domain_label_empty"  // leading, trailing or consecutive dots
+	SyntaxDomainLabelLong   SyntaxReason = "domain_label_long"   // a label of more than 63 octets
+	SyntaxDomainInvalid     SyntaxReason = "domain_invalid"      // bad characters, hyphens or numeric top level domain
+	SyntaxIPLiteralInvalid  SyntaxReason = "ip_literal_invalid"  // bad [IPv4] or [IPv6:...] domain literal
+)
+
+// Length limits of an address, in octets
+// see: https://datatracker.ietf.org/doc/html/rfc5321#section-4.5.3.1
+const (
+	maxEmailLength       = 254
+	maxLocalPartLength   = 64
+	maxDomainLabelLength = 63
+)
+
+// SyntaxError is returned by ValidateSyntax when the email address is not valid
+type SyntaxError struct {
+	Email  string       // address validated
+	Reason SyntaxReason // why it is not valid
+}
+
+// Error implements the error interface
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid email syntax %q: %s", e.Email, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidSyntax
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrInvalidSyntax
+}
+
+// ValidateSyntax checks the email address follows the RFC 5321/5322 mailbox syntax:
+// a dot-atom or quoted local part and a domain name or IP address literal.
+// UTF-8 characters are accepted as allowed by RFC 6531. A *SyntaxError is returned when not valid.
+func ValidateSyntax(email string) error {
+	if reason := syntaxReason(email); reason != "" {
+		return &SyntaxError{Email: email, Reason: reason}
+	}
+	return nil
+}
+
+// syntaxReason returns why the email is not valid, empty if it is
+func syntaxReason(email string) SyntaxReason {
+	if email == "" {
+		return SyntaxEmpty
+	}
+	if len(email) > maxEmailLength {
+		return SyntaxTooLong
+	}
+
+	// the local part may contain a quoted @, the domain can not
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return SyntaxMissingAt
+	}
+	local, domain := email[:at], email[at+1:]
+
+	if reason := localPartReason(local); reason != "" {
+		return reason
+	}
+	return domainReason(domain)
+}
+
+// localPartReason validates a dot-atom or quoted-string local part
+func localPartReason(local string) SyntaxReason {
+	switch {
+	case local == "":
+		return SyntaxLocalPartEmpty
+	case len(local) > maxLocalPartLength:
+		return SyntaxLocalPartTooLong
+	case len(local) >= 2 && local[0] == '"' && local[len(local)-1] == '"':
+		if !validQuotedString(local[1 : len(local)-1]) {
+			return SyntaxLocalPartInvalid
+		}
+		return ""
+	}
+
+	for _, atom := range strings.Split(local, ".") {
+		if atom == "" {
+			return SyntaxLocalPartInvalid
+		}
+		for i := 0; i < len(atom); i++ {
+			if !isAtext(atom[i]) {
+				return SyntaxLocalPartInvalid
+			}
+		}
+	}
+	return ""
+}
+
+// validQuotedString validates the content of a quoted string, quotes excluded
+func validQuotedString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			// quoted-pair, escapes the next printable character
+			i++
+			if i == len(s) || s[i] < ' ' || s[i] == 0x7f {
+				return false
+			}
+		case c == '"' || c < ' ' || c == 0x7f:
+			return false
+		}
+	}
+	return true
+}
+
+// isAtext reports whether c can be part of an unquoted local part atom
+func isAtext(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	case c >= 0x80: // UTF-8
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+// domainReason validates a domain name or an IP address literal
+func domainReason(domain string) SyntaxReason {
+	if domain == "" {
+		return SyntaxDomainEmpty
+	}
+	if domain[0] == '[' {
+		if !validIPLiteral(domain) {
+			return SyntaxIPLiteralInvalid
+		}
+		return ""
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return SyntaxDomainUnqualified
+	}
+	for _, label := range labels {
+		switch {
+		case label == "":
+			return SyntaxDomainLabelEmpty
+		case len(label) > maxDomainLabelLength:
+			return SyntaxDomainLabelLong
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return SyntaxDomainInvalid
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c >= 0x80) {
+				return SyntaxDomainInvalid
+			}
+		}
+	}
+
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return SyntaxDomainInvalid
+	}
+	return ""
+}
+
+// validIPLiteral validates [1.2.3.4] and [IPv6:::1] domain literals
+func validIPLiteral(domain string) bool {
+	if len(domain) < 2 || domain[len(domain)-1] != ']' {
+		return false
+	}
+	literal := domain[1 : len(domain)-1]
+
+	if strings.HasPrefix(literal, "IPv6:") {
+		addr := strings.TrimPrefix(literal, "IPv6:")
+		return net.ParseIP(addr) != nil && strings.Contains(addr, ":")
+	}
+	ip := net.ParseIP(literal)
+	return ip != nil && ip.To4() != nil && !strings.Contains(literal, ":")
+}
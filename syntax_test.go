@@ -0,0 +1,100 @@
+package kickbox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSyntax(t *testing.T) {
+	valid := []string{
+		"user@example.com",
+		"first.last+tag@sub.example.co.uk",
+		"!#$%&'*+-/=?^_`{|}~@example.com",
+		`"john doe"@example.com`,
+		`"quoted\"escaped@at"@example.com`,
+		"user@[192.168.0.1]",
+		"user@[IPv6:2001:db8::1]",
+		"josé@bücher.de",
+		"user@xn--bcher-kva.de",
+		strings.Repeat("a", 64) + "@example.com",
+	}
+	for _, email := range valid {
+		assert.Nil(t, kickbox.ValidateSyntax(email), email)
+	}
+
+	invalid := map[string]kickbox.SyntaxReason{
+		"":                                       kickbox.SyntaxEmpty,
+		strings.Repeat("a", 250) + "@test.com":   kickbox.SyntaxTooLong,
+		"user.example.com":                       kickbox.SyntaxMissingAt,
+		"@example.com":                           kickbox.SyntaxLocalPartEmpty,
+		strings.Repeat("a", 65) + "@example.com": kickbox.SyntaxLocalPartTooLong,
+		".user@example.com":                      kickbox.SyntaxLocalPartInvalid,
+		"first..last@example.com":                kickbox.SyntaxLocalPartInvalid,
+		"john doe@example.com":                   kickbox.SyntaxLocalPartInvalid,
+		`"unterminated\"@example.com`:            kickbox.SyntaxLocalPartInvalid,
+		"user@":                                  kickbox.SyntaxDomainEmpty,
+		"user@localhost":                         kickbox.SyntaxDomainUnqualified,
+		"user@example..com":                      kickbox.SyntaxDomainLabelEmpty,
+		"user@example.com.":                      kickbox.SyntaxDomainLabelEmpty,
+		"user@" + strings.Repeat("a", 64) + ".com": kickbox.SyntaxDomainLabelLong,
+		"user@-example.com":                        kickbox.SyntaxDomainInvalid,
+		"user@exa_mple.com":                        kickbox.SyntaxDomainInvalid,
+		"user@1.2.3.4":                             kickbox.SyntaxDomainInvalid,
+		"user@[300.1.1.1]":                         kickbox.SyntaxIPLiteralInvalid,
+		"user@[IPv6:1.2.3.4]":                      kickbox.SyntaxIPLiteralInvalid,
+		"user@[::1]":                               kickbox.SyntaxIPLiteralInvalid,
+	}
+	for email, reason := range invalid {
+		err := kickbox.ValidateSyntax(email)
+		var syntaxErr *kickbox.SyntaxError
+		if assert.True(t, errors.As(err, &syntaxErr), email) {
+			assert.Equal(t, reason, syntaxErr.Reason, email)
+			assert.True(t, errors.Is(err, kickbox.ErrInvalidSyntax))
+		}
+	}
+
+	assert.EqualError(t, kickbox.ValidateSyntax("user@localhost"), `invalid email syntax "user@localhost": domain_unqualified`)
+}
+
+func TestVerifyValidateSyntaxLocally(t *testing.T) {
+	var calls int32
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(`{"result":"deliverable","success":true}`))
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.ValidateSyntaxLocally(),
+	)
+	assert.Nil(t, err)
+
+	header, resp, err := client.Verify(context.TODO(), "user@@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, header.HTTPStatus)
+	assert.Equal(t, kickbox.ResultUndeliverable, resp.Result)
+	assert.Equal(t, kickbox.ReasonInvalidEmail, resp.Reason)
+	assert.Equal(t, "user@", resp.User)
+	assert.Equal(t, "example.com", resp.Domain)
+	assert.True(t, resp.Success)
+	assert.Empty(t, resp.Message)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+	_, resp, err = client.Verify(context.TODO(), "user@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, kickbox.ResultDeliverable, resp.Result)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,289 @@
+package kickbox
+
+import (
+	"context"
+	"sync"
+)
+
+// VerifyManyResult holds the verification result of a single email
+type VerifyManyResult struct {
+	Index    int    // position of the email in the input
+	Email    string // email as received in the input
+	Headers  *ResponseVerifyHeaders
+	Response *ResponseVerify
+	Err      error
+}
+
+// VerifyManyProgress is reported every time a result is emitted or a duplicate skipped
+type VerifyManyProgress struct {
+	Total      int // number of input emails, -1 when reading from a channel
+	Completed  int // results emitted
+	Failed     int // results emitted with an error
+	Duplicates int // emails skipped because they were already verified
+}
+
+// VerifyManyRequestOptions holds the optional parameters for the VerifyMany request
+type VerifyManyRequestOptions struct {
+	workers       int
+	deduplicate   bool
+	preserveOrder bool
+	stopOnError   bool
+	onProgress    func(VerifyManyProgress)
+	verifyOptions []VerifyOption
+}
+
+// VerifyManyOption option type
+type VerifyManyOption func(*VerifyManyRequestOptions)
+
+// Workers sets the number of concurrent verifications.
+// Default: the maximum concurrent connections of the client
+func Workers(n int) VerifyManyOption {
+	return func(o *VerifyManyRequestOptions) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// Deduplicate skips the emails that have already been sent to verify
+func Deduplicate() VerifyManyOption {
+	return func(o *VerifyManyRequestOptions) {
+		o.deduplicate = true
+	}
+}
+
+// preserveOrderWindow is the number of results, per worker, buffered ahead of the
+// next one in order before the dispatch is held
+const preserveOrderWindow = 4
+
+// PreserveOrder emits the results in the same order as the input. While a verification
+// is slow the following ones go on, up to 4 per worker, then the dispatch waits for it.
+func PreserveOrder() VerifyManyOption {
+	return func(o *VerifyManyRequestOptions) {
+		o.preserveOrder = true
+	}
+}
+
+// StopOnError stops verifying after the first result with an error is emitted.
+// By default the verification continues on errors.
+func StopOnError() VerifyManyOption {
+	return func(o *VerifyManyRequestOptions) {
+		o.stopOnError = true
+	}
+}
+
+// OnProgress sets a callback to report the progress, calls are never concurrent
+func OnProgress(fn func(VerifyManyProgress)) VerifyManyOption {
+	return func(o *VerifyManyRequestOptions) {
+		o.onProgress = fn
+	}
+}
+
+// WithVerifyOptions sets the options used on every single verification
+func WithVerifyOptions(opts ...VerifyOption) VerifyManyOption {
+	return func(o *VerifyManyRequestOptions) {
+		o.verifyOptions = append(o.verifyOptions, opts...)
+	}
+}
+
+// verifyFunc is the signature of Verifier.Verify
+type verifyFunc func(ctx context.Context, email string, opts ...VerifyOption) (*ResponseVerifyHeaders, *ResponseVerify, error)
+
+// newVerifyManyOptions applies the options over the defaults
+func newVerifyManyOptions(workers int, opts []VerifyManyOption) VerifyManyRequestOptions {
+	options := VerifyManyRequestOptions{
+		workers: workers,
+	}
+	for _, apply := range opts {
+		apply(&options)
+	}
+	return options
+}
+
+// emailSource returns the channel of emails to verify, it must stop sending once ctx is done
+type emailSource func(ctx context.Context) <-chan string
+
+// sliceSource streams a slice of emails
+func sliceSource(emails []string) emailSource {
+	return func(ctx context.Context) <-chan string {
+		ch := make(chan string)
+		go func() {
+			defer close(ch)
+			for _, email := range emails {
+				select {
+				case ch <- email:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return ch
+	}
+}
+
+// chanSource streams the emails received from a channel owned by the caller
+func chanSource(emails <-chan string) emailSource {
+	return func(context.Context) <-chan string {
+		return emails
+	}
+}
+
+// verifyMany fans out the emails to a pool of workers calling verify.
+// The returned channel is closed when all the results are emitted or the context is done.
+func verifyMany(ctx context.Context, verify verifyFunc, source emailSource, total int, options VerifyManyRequestOptions) <-chan VerifyManyResult {
+	out := make(chan VerifyManyResult)
+
+	type job struct {
+		seq   int // dispatch order, used to preserve the order
+		index int // input position
+		email string
+	}
+	type result struct {
+		seq int
+		VerifyManyResult
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	emails := source(ctx)
+
+	var mu sync.Mutex
+	progress := VerifyManyProgress{Total: total}
+	report := func(update func(*VerifyManyProgress)) {
+		mu.Lock()
+		defer mu.Unlock()
+		update(&progress)
+		if options.onProgress != nil {
+			options.onProgress(progress)
+		}
+	}
+
+	// window bounds the results buffered to preserve the order, a slot is taken by every
+	// job dispatched and released once its result is emitted in order
+	var window chan struct{}
+	if options.preserveOrder {
+		window = make(chan struct{}, options.workers*preserveOrderWindow)
+	}
+
+	// Dispatcher
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		seen := map[string]struct{}{}
+		seq := 0
+		for index := 0; ; index++ {
+			var email string
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-emails:
+				if !ok {
+					return
+				}
+				email = e
+			}
+
+			if options.deduplicate {
+				key := EmailKey(email)
+				if _, found := seen[key]; found {
+					report(func(p *VerifyManyProgress) { p.Duplicates++ })
+					continue
+				}
+				seen[key] = struct{}{}
+			}
+
+			if window != nil {
+				select {
+				case window <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case jobs <- job{seq: seq, index: index, email: email}:
+				seq++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Workers
+	results := make(chan result)
+	wg := sync.WaitGroup{}
+	for i := 0; i < options.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				headers, resp, err := verify(ctx, j.email, options.verifyOptions...)
+				results <- result{
+					seq: j.seq,
+					VerifyManyResult: VerifyManyResult{
+						Index:    j.index,
+						Email:    j.email,
+						Headers:  headers,
+						Response: resp,
+						Err:      err,
+					},
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Collector
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		stopped := false
+		emit := func(r VerifyManyResult) {
+			if stopped {
+				return
+			}
+			select {
+			case out <- r:
+			case <-ctx.Done():
+				stopped = true
+				return
+			}
+			report(func(p *VerifyManyProgress) {
+				p.Completed++
+				if r.Err != nil {
+					p.Failed++
+				}
+			})
+			if r.Err != nil && options.stopOnError {
+				stopped = true
+				cancel()
+			}
+		}
+
+		pending := map[int]VerifyManyResult{}
+		next := 0
+		for r := range results {
+			if !options.preserveOrder {
+				emit(r.VerifyManyResult)
+				continue
+			}
+
+			pending[r.seq] = r.VerifyManyResult
+			for {
+				pr, found := pending[next]
+				if !found {
+					break
+				}
+				delete(pending, next)
+				next++
+				emit(pr)
+				<-window
+			}
+		}
+	}()
+
+	return out
+}
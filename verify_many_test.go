@@ -0,0 +1,215 @@
+package kickbox_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wakumaku/kickbox"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestVerifyManySandbox(t *testing.T) {
+	emails := []string{
+		"deliverable@example.com",
+		"undeliverable@example.com",
+		"Deliverable@Example.com",
+		"role@example.com",
+		" deliverable@example.com ",
+	}
+
+	var last kickbox.VerifyManyProgress
+	results := kickbox.NewSandbox().VerifyMany(context.TODO(), emails,
+		kickbox.Deduplicate(),
+		kickbox.Workers(2),
+		kickbox.OnProgress(func(p kickbox.VerifyManyProgress) { last = p }),
+	)
+
+	got := map[int]kickbox.Result{}
+	for r := range results {
+		assert.Nil(t, r.Err)
+		got[r.Index] = r.Response.Result
+	}
+
+	assert.Equal(t, map[int]kickbox.Result{
+		0: kickbox.ResultDeliverable,
+		1: kickbox.ResultUndeliverable,
+		3: kickbox.ResultRisky,
+	}, got)
+	assert.Equal(t, kickbox.VerifyManyProgress{Total: 5, Completed: 3, Duplicates: 2}, last)
+}
+
+func TestVerifyManyPreserveOrder(t *testing.T) {
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		// the first emails take longer to be verified
+		var i int
+		_, _ = fmt.Sscanf(r.URL.Query().Get("email"), "user%d@example.com", &i)
+		time.Sleep(time.Duration(10-i) * 5 * time.Millisecond)
+		rw.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(rw, `{"email":%q,"result":"deliverable","success":true}`, r.URL.Query().Get("email"))
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.CustomRateLimiter(rate.NewLimiter(rate.Inf, 1)),
+	)
+	assert.Nil(t, err)
+
+	var emails []string
+	for i := 0; i < 10; i++ {
+		emails = append(emails, fmt.Sprintf("user%d@example.com", i))
+	}
+
+	var got []string
+	for r := range client.VerifyMany(context.TODO(), emails, kickbox.PreserveOrder(), kickbox.Workers(5)) {
+		assert.Nil(t, r.Err)
+		assert.Equal(t, r.Email, r.Response.Email)
+		assert.Equal(t, http.StatusOK, r.Headers.HTTPStatus)
+		got = append(got, r.Email)
+	}
+	assert.Equal(t, emails, got)
+}
+
+func TestVerifyManyPreserveOrderBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		// the first email is stuck, e.g. retrying
+		if r.URL.Query().Get("email") == "user0@example.com" {
+			<-release
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(rw, `{"email":%q,"result":"deliverable","success":true}`, r.URL.Query().Get("email"))
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.CustomRateLimiter(rate.NewLimiter(rate.Inf, 1)),
+	)
+	assert.Nil(t, err)
+
+	var sent int32
+	emails := make(chan string)
+	go func() {
+		defer close(emails)
+		for i := 0; i < 100; i++ {
+			emails <- fmt.Sprintf("user%d@example.com", i)
+			atomic.AddInt32(&sent, 1)
+		}
+	}()
+
+	results := client.VerifyManyChan(context.TODO(), emails, kickbox.PreserveOrder(), kickbox.Workers(2))
+
+	// 4 results per worker are buffered, the next email is held by the dispatcher
+	time.Sleep(100 * time.Millisecond)
+	assert.LessOrEqual(t, atomic.LoadInt32(&sent), int32(2*4+1))
+
+	close(release)
+	var got int
+	for r := range results {
+		assert.Nil(t, r.Err)
+		assert.Equal(t, fmt.Sprintf("user%d@example.com", got), r.Email)
+		got++
+	}
+	assert.Equal(t, 100, got)
+}
+
+func TestVerifyManyStopOnError(t *testing.T) {
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Query().Get("email"), "fail") {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(`{"result":"deliverable","success":true}`))
+	}
+
+	svr := httptest.NewServer(http.HandlerFunc(handler))
+	defer svr.Close()
+
+	client, err := kickbox.New("apikey",
+		kickbox.OverrideBaseURL(svr.URL),
+		kickbox.CustomRateLimiter(rate.NewLimiter(rate.Inf, 1)),
+	)
+	assert.Nil(t, err)
+
+	emails := []string{"a@example.com", "b@example.com", "fail@example.com", "c@example.com", "d@example.com"}
+
+	// continue on error
+	var failed int
+	var total int
+	for r := range client.VerifyMany(context.TODO(), emails) {
+		total++
+		if r.Err != nil {
+			failed++
+		}
+	}
+	assert.Equal(t, 5, total)
+	assert.Equal(t, 1, failed)
+
+	// stop on the first error
+	var got []string
+	for r := range client.VerifyMany(context.TODO(), emails, kickbox.StopOnError(), kickbox.PreserveOrder(), kickbox.Workers(1)) {
+		got = append(got, r.Email)
+		if r.Email == "fail@example.com" {
+			assert.NotNil(t, r.Err)
+		}
+	}
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "fail@example.com"}, got)
+}
+
+func TestVerifyManyChan(t *testing.T) {
+	emails := make(chan string)
+	go func() {
+		defer close(emails)
+		for i := 0; i < 30; i++ {
+			emails <- fmt.Sprintf("user%d+undeliverable@example.com", i%10)
+		}
+	}()
+
+	var last kickbox.VerifyManyProgress
+	total := 0
+	results := kickbox.NewSandbox().VerifyManyChan(context.TODO(), emails,
+		kickbox.Deduplicate(),
+		kickbox.OnProgress(func(p kickbox.VerifyManyProgress) { last = p }),
+	)
+	for r := range results {
+		assert.Nil(t, r.Err)
+		assert.Equal(t, kickbox.ResultUndeliverable, r.Response.Result)
+		total++
+	}
+
+	assert.Equal(t, 10, total)
+	assert.Equal(t, kickbox.VerifyManyProgress{Total: -1, Completed: 10, Duplicates: 20}, last)
+}
+
+func TestVerifyManyContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	emails := make([]string, 1000)
+	for i := range emails {
+		emails[i] = fmt.Sprintf("user%d@example.com", i)
+	}
+
+	total := 0
+	for range kickbox.NewSandbox().VerifyMany(ctx, emails, kickbox.Workers(1)) {
+		total++
+		if total == 10 {
+			cancel()
+		}
+	}
+	assert.True(t, total < len(emails))
+}